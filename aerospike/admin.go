@@ -0,0 +1,96 @@
+package aerospike
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	aero "github.com/aerospike/aerospike-client-go/v6"
+	"github.com/aerospike/aerospike-client-go/v6/types"
+	"gitlab.com/alielgamal/hfid"
+	"sort"
+)
+
+// Get Implemented using a single Operate command that reads the generator properties and the HyperLogLog count
+// estimate
+func (gs GeneratorStore) Get(ctx context.Context, gName string) (hfid.Generator, int64, bool, error) {
+	g := hfid.Generator{Name: gName}
+	key, aeroErr := aero.NewKey(gs.Namespace, gs.Set, gName)
+	if aeroErr != nil {
+		return g, 0, false, aeroErr
+	}
+
+	r, err := gs.operate(ctx, key, aero.GetBinOp(gBin), aero.HLLGetCountOp(hllBin))
+	if isKeyNotFound(err) {
+		return g, 0, false, nil
+	}
+	if err != nil {
+		return g, 0, false, err
+	}
+	g, c, err := parseRecord(g, r)
+	return g, c, true, err
+}
+
+// List Implemented using a scan of the Set that returns the keys of the records only. Generators that have not been
+// written since SendKey was enabled (i.e. by InsertOrGet or Upsert) don't have their key stored, hence they are skipped.
+func (gs GeneratorStore) List(ctx context.Context) ([]string, error) {
+	p := aero.NewScanPolicy()
+	p.IncludeBinData = false
+	rs, aeroErr := gs.Client.ScanAll(p, gs.Namespace, gs.Set)
+	if aeroErr != nil {
+		return nil, aeroErr
+	}
+	defer rs.Close()
+
+	var names []string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res, ok := <-rs.Results():
+			if !ok {
+				sort.Strings(names)
+				return names, nil
+			}
+			if res.Err != nil {
+				return nil, res.Err
+			}
+			if v := res.Record.Key.Value(); v != nil {
+				names = append(names, v.String())
+			}
+		}
+	}
+}
+
+// Delete Implemented using an Operate command that deletes the generator record, followed by deleting the records of
+// the map shards if the generator's Membership is a MapMembership. The records of a SetMembership cannot be found
+// without scanning the companion set, hence they are kept and the HFIDs they hold are still considered generated if
+// the generator is created again.
+func (gs GeneratorStore) Delete(ctx context.Context, gName string) error {
+	keys := []string{gName}
+	m := gs.membership(gName)
+	if m.Type == MapMembership {
+		for i := 0; i < m.Shards; i++ {
+			keys = append(keys, fmt.Sprintf("%s#%d", gName, i))
+		}
+	}
+
+	for i, k := range keys {
+		set := gs.Set
+		if i > 0 {
+			set = m.Set
+		}
+		key, aeroErr := aero.NewKey(gs.Namespace, set, k)
+		if aeroErr != nil {
+			return aeroErr
+		}
+		if _, err := gs.operate(ctx, key, aero.DeleteOp()); err != nil && !isKeyNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func isKeyNotFound(err error) bool {
+	var aeroErr aero.Error
+	return errors.As(err, &aeroErr) && aeroErr.Matches(types.KEY_NOT_FOUND_ERROR)
+}
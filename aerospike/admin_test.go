@@ -0,0 +1,78 @@
+package aerospike
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"testing"
+)
+
+func TestGeneratorStore_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Doesn't create a generator that doesn't exist", func(t *testing.T) {
+		gs := prepareStore(t)
+		_, _, found, err := gs.Get(ctx, "test")
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		names, err := gs.List(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, names)
+	})
+
+	t.Run("Returns an existing generator and its count", func(t *testing.T) {
+		gs := prepareStore(t)
+		g := hfid.Generator{Name: "test", Prefix: "t-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2}
+		assert.NoError(t, gs.Upsert(ctx, g))
+		_, err := gs.Add(ctx, 1, g.Name)
+		assert.NoError(t, err)
+
+		foundG, c, found, err := gs.Get(ctx, g.Name)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, g, foundG)
+		assert.Equal(t, int64(1), c)
+	})
+}
+
+func TestGeneratorStore_List(t *testing.T) {
+	ctx := context.Background()
+	gs := prepareStore(t)
+	for _, name := range []string{"b", "a"} {
+		_, _, err := gs.InsertOrGet(ctx, hfid.Generator{Name: name, Prefix: "t-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2})
+		assert.NoError(t, err)
+	}
+
+	names, err := gs.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestGeneratorStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	gs := prepareStore(t)
+	m := Membership{Type: MapMembership, Set: gs.Set[:len(gs.Set)/2] + "-ids", Shards: 2}
+	_ = aerospikeClient.Truncate(nil, gs.Namespace, m.Set, nil)
+	gs.Memberships = map[string]Membership{"test": m}
+
+	g := hfid.Generator{Name: "test", Prefix: "t-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2}
+	_, _, err := gs.InsertOrGet(ctx, g)
+	assert.NoError(t, err)
+	_, err = gs.Add(ctx, 1, g.Name)
+	assert.NoError(t, err)
+
+	assert.NoError(t, gs.Delete(ctx, g.Name))
+	_, _, found, err := gs.Get(ctx, g.Name)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// The map shards are deleted too, hence the HFID is new again
+	isNew, err := gs.Add(ctx, 1, g.Name)
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+
+	t.Run("Ignores generators that don't exist", func(t *testing.T) {
+		assert.NoError(t, gs.Delete(ctx, "missing"))
+	})
+}
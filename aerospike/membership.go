@@ -0,0 +1,93 @@
+package aerospike
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	aero "github.com/aerospike/aerospike-client-go/v6"
+	"github.com/aerospike/aerospike-client-go/v6/types"
+)
+
+const idsBin = "n"
+const defaultShards = 16
+
+// MembershipType The structure used by GeneratorStore to track the HFIDs added to a generator
+type MembershipType string
+
+// HyperLogLogMembership tracks the HFIDs using the generator's HLL bin only. It is the cheapest option, but the HLL may
+// report a new HFID as added before (which only causes an extra retry) or, rarely, an existing HFID as new.
+const HyperLogLogMembership MembershipType = "hll"
+
+// MapMembership tracks the HFIDs exactly as keys of map bins that are sharded across multiple records in the
+// membership Set. An HFID is added using MapPutOp with CREATE_ONLY flag.
+const MapMembership MembershipType = "map"
+
+// SetMembership tracks the HFIDs exactly as individual records in the membership Set. An HFID is added by creating its
+// record using CREATE_ONLY record exists action.
+const SetMembership MembershipType = "set"
+
+// Membership Specifies how the HFIDs added to a generator are tracked. Regardless of the type, the generator's HLL bin
+// is always updated since it is used to estimate the number of HFIDs generated.
+type Membership struct {
+	Type MembershipType
+
+	// Set is the name of the companion set holding the records of the map shards or the HFIDs. The GeneratorStore's Set
+	// followed by -ids is used when empty.
+	Set string
+
+	// Shards is the number of records the map bin is sharded across when using MapMembership. 16 shards are used when
+	// zero.
+	Shards int
+}
+
+func (gs GeneratorStore) membership(gName string) Membership {
+	m := gs.Memberships[gName]
+	if m.Type == "" {
+		m.Type = HyperLogLogMembership
+	}
+	if m.Set == "" {
+		m.Set = gs.Set + "-ids"
+	}
+	if m.Shards <= 0 {
+		m.Shards = defaultShards
+	}
+	return m
+}
+
+// addMember adds hfid to the map or the set tracking the HFIDs of the generator named gName. It returns true if hfid
+// has been added, false if it existed before.
+func (gs GeneratorStore) addMember(ctx context.Context, m Membership, hfid int64, gName string) (bool, error) {
+	switch m.Type {
+	case MapMembership:
+		key, aeroErr := aero.NewKey(gs.Namespace, m.Set, fmt.Sprintf("%s#%d", gName, hfid%int64(m.Shards)))
+		if aeroErr != nil {
+			return false, aeroErr
+		}
+		_, err := gs.operate(ctx, key, aero.MapPutOp(
+			aero.NewMapPolicyWithFlags(aero.MapOrder.UNORDERED, aero.MapWriteFlagsCreateOnly), idsBin, hfid, 1))
+		return isNew(err, types.FAIL_ELEMENT_EXISTS)
+	case SetMembership:
+		key, aeroErr := aero.NewKey(gs.Namespace, m.Set, fmt.Sprintf("%s#%d", gName, hfid))
+		if aeroErr != nil {
+			return false, aeroErr
+		}
+		p, err := gs.writePolicy(ctx)
+		if err != nil {
+			return false, err
+		}
+		p.RecordExistsAction = aero.CREATE_ONLY
+		_, err = gs.operateWithPolicy(ctx, p, key, aero.PutOp(aero.NewBin(idsBin, hfid)))
+		return isNew(err, types.KEY_EXISTS_ERROR)
+	default:
+		return false, fmt.Errorf("unknown membership type '%s' for Generator name '%s'", m.Type, gName)
+	}
+}
+
+// isNew converts the error of a create only operation to whether the item has been created or not
+func isNew(err error, existsCode types.ResultCode) (bool, error) {
+	var aeroErr aero.Error
+	if errors.As(err, &aeroErr) && aeroErr.Matches(existsCode) {
+		return false, nil
+	}
+	return err == nil, err
+}
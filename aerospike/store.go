@@ -3,11 +3,16 @@ package aerospike
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	aero "github.com/aerospike/aerospike-client-go/v6"
+	"github.com/aerospike/aerospike-client-go/v6/types"
 	"github.com/hashicorp/go-multierror"
 	"gitlab.com/alielgamal/hfid"
+	"math"
+	"math/bits"
 	"reflect"
+	"time"
 )
 
 const gBin = "g"
@@ -15,25 +20,172 @@ const prefixKey = "p"
 const encodingKey = "e"
 const minLengthKey = "m"
 const lengthKey = "l"
+const indexBitsKey = "i"
+const minHashBitsKey = "mh"
 const hllBin = "h"
 
+const defaultIndexBits = 16
+const defaultMinHashBits = 4
+const minIndexBits = 4
+const maxIndexBits = 16
+const defaultMaxErrorRate = 0.01
+
+// existingBits lets HLL operations use the index and minhash bits of the existing HLL bin
+const existingBits = -1
+
 // GeneratorStore A Struct that wraps an Aerospike Client and implements the GeneratorStore interface provided by
 // HFID. This implementation utilizes a single set with a bin for the generator's properties and another bin for the
-// generator's associated HLL
+// generator's associated HLL. Generators can track their HFIDs exactly using a map or a set by configuring their
+// Membership. The deadline of the context passed to each method is mapped to the timeouts of the WritePolicy, and
+// cancelling the context aborts waiting for the in-flight operation. The aborted operation still completes in the
+// background, which is safe since an Add that commits after the cancellation only uses up an HFID that is never
+// returned, it never causes a duplicate.
 type GeneratorStore struct {
 	Client    *aero.Client
 	Namespace string
 	Set       string
+
+	// WritePolicy is the default policy used for all the operations (e.g. to tune timeouts, retries or commit level).
+	// The client's default WritePolicy is used when nil. SendKey is always enabled when writing the generators so that
+	// List can return their names.
+	WritePolicy *aero.WritePolicy
+
+	// Memberships maps generator names to the structure used to track their HFIDs exactly. HyperLogLogMembership is
+	// used for generators that are not found.
+	Memberships map[string]Membership
+
+	// ExpectedCardinalities maps generator names to the number of HFIDs they are expected to generate. It is used to
+	// choose the index bits of the generator's HLL when it is created. Generators that are not found use 16 index bits
+	// and 4 minhash bits. Use ReinitializeHLL to change the precision of an existing generator.
+	ExpectedCardinalities map[string]int64
+
+	// MaxErrorRate is the maximum standard error of the HLL count estimate of the generators configured in
+	// ExpectedCardinalities. 1% is used when zero.
+	MaxErrorRate float64
+}
+
+// hllBits returns the HLL index and minhash bits to use for the generator named gName. The index bits are the fewest
+// whose standard error, 1.04/sqrt(2^indexBits), is at most MaxErrorRate, increased so that there are at least as many
+// registers as the expected cardinality, which keeps the estimate in its exact linear counting range and reduces the
+// HFIDs wrongly reported as not new. The index bits are capped at 16 which is the maximum supported by Aerospike. The
+// minhash bits don't improve the count estimate, hence 4 are always used.
+func (gs GeneratorStore) hllBits(gName string) (int, int) {
+	expected, found := gs.ExpectedCardinalities[gName]
+	if !found || expected <= 0 {
+		return defaultIndexBits, defaultMinHashBits
+	}
+
+	maxErrorRate := gs.MaxErrorRate
+	if maxErrorRate <= 0 {
+		maxErrorRate = defaultMaxErrorRate
+	}
+	errorBits := int(math.Ceil(math.Log2(math.Pow(1.04/maxErrorRate, 2))))
+	cardinalityBits := bits.Len64(uint64(expected - 1))
+	if errorBits > cardinalityBits {
+		return clamp(errorBits, minIndexBits, maxIndexBits), defaultMinHashBits
+	}
+	return clamp(cardinalityBits, minIndexBits, maxIndexBits), defaultMinHashBits
+}
+
+func clamp(n int, min int, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// initHLLOps returns the operations that create the generator's HLL with its chosen precision and record the precision
+// in the generator bin only if they don't exist. The precision of an existing HLL is left untouched, hence it is only
+// changed by ReinitializeHLL.
+func (gs GeneratorStore) initHLLOps(gName string) []*aero.Operation {
+	indexBits, minHashBits := gs.hllBits(gName)
+	return []*aero.Operation{
+		aero.MapPutItemsOp(aero.NewMapPolicyWithFlags(aero.MapOrder.UNORDERED, aero.MapWriteFlagsCreateOnly|aero.MapWriteFlagsNoFail),
+			gBin, map[interface{}]interface{}{
+				indexBitsKey:   indexBits,
+				minHashBitsKey: minHashBits,
+			}),
+		aero.HLLInitOp(aero.NewHLLPolicy(aero.HLLWriteFlagsCreateOnly|aero.HLLWriteFlagsNoFail), hllBin, indexBits, minHashBits),
+	}
+}
+
+// writePolicy returns a copy of the default WritePolicy with its timeouts capped by the context deadline
+func (gs GeneratorStore) writePolicy(ctx context.Context) (*aero.WritePolicy, error) {
+	var p aero.WritePolicy
+	if gs.WritePolicy != nil {
+		p = *gs.WritePolicy
+	} else if gs.Client != nil && gs.Client.DefaultWritePolicy != nil {
+		p = *gs.Client.DefaultWritePolicy
+	} else {
+		p = *aero.NewWritePolicy(0, 0)
+	}
+
+	if d, ok := ctx.Deadline(); ok {
+		t := time.Until(d)
+		if t <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		if p.TotalTimeout == 0 || p.TotalTimeout > t {
+			p.TotalTimeout = t
+		}
+		if p.SocketTimeout == 0 || p.SocketTimeout > t {
+			p.SocketTimeout = t
+		}
+	}
+	return &p, nil
+}
+
+// operate runs Client.Operate using the WritePolicy derived from the context and returns as soon as either the
+// operation completes or the context is done.
+func (gs GeneratorStore) operate(ctx context.Context, key *aero.Key, ops ...*aero.Operation) (*aero.Record, error) {
+	p, err := gs.writePolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return gs.operateWithPolicy(ctx, p, key, ops...)
+}
+
+// operateWithPolicy is the same as operate but uses the passed WritePolicy as is
+func (gs GeneratorStore) operateWithPolicy(ctx context.Context, p *aero.WritePolicy, key *aero.Key, ops ...*aero.Operation) (*aero.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		r   *aero.Record
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		r, aeroErr := gs.Client.Operate(p, key, ops...)
+		if aeroErr != nil {
+			done <- result{r, aeroErr}
+			return
+		}
+		done <- result{r, nil}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-done:
+		return res.r, res.err
+	}
 }
 
-// InsertOrGet Implemented using a single Operate command that creates the generator if it doesn't exist and reads the
-// generator properties and the HyperLogLog count estimate.
-func (gs GeneratorStore) InsertOrGet(_ context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+// InsertOrGet Implemented using a single Operate command that creates the generator and its HyperLogLog if they don't
+// exist and reads the generator properties and the HyperLogLog count estimate.
+func (gs GeneratorStore) InsertOrGet(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
 	key, aeroErr := aero.NewKey(gs.Namespace, gs.Set, g.Name)
-	merr := multierror.Append(aeroErr)
+	if aeroErr != nil {
+		return hfid.Generator{}, 0, aeroErr
+	}
 
 	// Write the record to Aerospike spike ONLY if it doesn't exist.
-	r, aeroErr := gs.Client.Operate(nil, key,
+	ops := append([]*aero.Operation{
 		aero.MapPutItemsOp(aero.NewMapPolicyWithFlags(aero.MapOrder.UNORDERED, aero.MapWriteFlagsCreateOnly|aero.MapWriteFlagsNoFail),
 			gBin, map[interface{}]interface{}{
 				prefixKey:    g.Prefix,
@@ -41,20 +193,35 @@ func (gs GeneratorStore) InsertOrGet(_ context.Context, g hfid.Generator) (hfid.
 				minLengthKey: g.MinLength,
 				lengthKey:    g.Length,
 			}),
+	}, gs.initHLLOps(g.Name)...)
+	ops = append(ops,
 		// Read the generator details
 		aero.GetBinOp(gBin),
 		// Get the HLL Count
 		aero.HLLGetCountOp(hllBin),
 	)
-	merr = multierror.Append(aeroErr, aeroErr)
-	if merr.ErrorOrNil() != nil {
-		return hfid.Generator{}, 0, merr.ErrorOrNil()
+	p, err := gs.writePolicy(ctx)
+	if err != nil {
+		return hfid.Generator{}, 0, err
 	}
+	p.SendKey = true
+	r, err := gs.operateWithPolicy(ctx, p, key, ops...)
+	if err != nil {
+		return hfid.Generator{}, 0, err
+	}
+
+	return parseRecord(g, r)
+}
 
-	storedG := r.Bins[gBin].([]interface{})[1].(map[interface{}]interface{})
+// parseRecord reads the generator properties and the HyperLogLog count estimate returned by an Operate command
+func parseRecord(g hfid.Generator, r *aero.Record) (hfid.Generator, int64, error) {
+	storedG, ok := lastResult(r.Bins[gBin]).(map[interface{}]interface{})
+	if !ok {
+		return hfid.Generator{}, 0, fmt.Errorf("unexpected generator result %v of type %v", r.Bins[gBin], reflect.TypeOf(r.Bins[gBin]))
+	}
 
 	p, err := toString(storedG[prefixKey])
-	merr = multierror.Append(err)
+	merr := multierror.Append(err)
 	g.Prefix = p
 
 	e, err := toString(storedG[encodingKey])
@@ -69,33 +236,92 @@ func (gs GeneratorStore) InsertOrGet(_ context.Context, g hfid.Generator) (hfid.
 	merr = multierror.Append(merr, err)
 	g.Length = uint8(l)
 
-	switch r.Bins[hllBin].(type) {
+	count := lastResult(r.Bins[hllBin])
+	switch count.(type) {
 	case nil:
 		return g, 0, merr.ErrorOrNil()
 	case int:
-		return g, int64(r.Bins[hllBin].(int)), merr.ErrorOrNil()
+		return g, int64(count.(int)), merr.ErrorOrNil()
 	case int64:
-		return g, r.Bins[hllBin].(int64), merr.ErrorOrNil()
+		return g, count.(int64), merr.ErrorOrNil()
 	default:
-		return hfid.Generator{}, 0, multierror.Append(merr, fmt.Errorf("unexpected hll count result %v of type %v", r.Bins[hllBin], reflect.TypeOf(r.Bins[hllBin]))).ErrorOrNil()
+		return hfid.Generator{}, 0, multierror.Append(merr, fmt.Errorf("unexpected hll count result %v of type %v", count, reflect.TypeOf(count))).ErrorOrNil()
 	}
 }
 
-// Upsert Implemented using a single MapPutItemsOp command
-func (gs GeneratorStore) Upsert(_ context.Context, g hfid.Generator) error {
-	key, err := aero.NewKey(gs.Namespace, gs.Set, g.Name)
-	merr := multierror.Append(err)
+// Upsert Implemented using an Operate command that reads the generator and its record generation, followed by an
+// Operate command that puts the generator properties and creates its HyperLogLog if it doesn't exist. The write
+// expects the record generation to be unchanged (or the record to be created) so a hfid.ConflictError is returned if
+// the generator has been modified concurrently. A hfid.ConflictError is also returned if the stored Length is greater
+// than the Length of g to prevent stale nodes from shrinking the generator.
+func (gs GeneratorStore) Upsert(ctx context.Context, g hfid.Generator) error {
+	key, aeroErr := aero.NewKey(gs.Namespace, gs.Set, g.Name)
+	if aeroErr != nil {
+		return aeroErr
+	}
 
-	_, err = gs.Client.Operate(nil, key, aero.MapPutItemsOp(aero.DefaultMapPolicy(), gBin,
+	p, err := gs.writePolicy(ctx)
+	if err != nil {
+		return err
+	}
+	p.SendKey = true
+	r, err := gs.operateWithPolicy(ctx, p, key, aero.GetBinOp(gBin))
+	switch {
+	case isKeyNotFound(err):
+		p.RecordExistsAction = aero.CREATE_ONLY
+	case err != nil:
+		return err
+	default:
+		storedG, _ := r.Bins[gBin].(map[interface{}]interface{})
+		if l, err := toInt(storedG[lengthKey]); err == nil && l > int(g.Length) {
+			return hfid.ConflictError{Name: g.Name, Reason: fmt.Sprintf("stored Length %d is greater than %d", l, g.Length)}
+		}
+		p.GenerationPolicy = aero.EXPECT_GEN_EQUAL
+		p.Generation = r.Generation
+	}
+
+	ops := append([]*aero.Operation{aero.MapPutItemsOp(aero.DefaultMapPolicy(), gBin,
 		map[interface{}]interface{}{
 			prefixKey:    g.Prefix,
 			encodingKey:  g.Encoding,
 			minLengthKey: g.MinLength,
 			lengthKey:    g.Length,
-		}))
-	merr = multierror.Append(err, err)
+		})}, gs.initHLLOps(g.Name)...)
+	_, err = gs.operateWithPolicy(ctx, p, key, ops...)
+	var conflictErr aero.Error
+	if errors.As(err, &conflictErr) && conflictErr.Matches(types.GENERATION_ERROR, types.KEY_EXISTS_ERROR) {
+		return hfid.ConflictError{Name: g.Name, Reason: conflictErr.Error()}
+	}
+	return err
+}
+
+// ReinitializeHLL resets the HyperLogLog of the generator named gName using the precision chosen from its expected
+// cardinality, and records the precision in the generator bin. Use it to migrate generators created before configuring
+// ExpectedCardinalities. Resetting the HyperLogLog forgets the HFIDs generated so far, hence pass them as hfids to add
+// them back to the new HyperLogLog, otherwise they may be generated again.
+func (gs GeneratorStore) ReinitializeHLL(ctx context.Context, gName string, hfids ...int64) error {
+	key, aeroErr := aero.NewKey(gs.Namespace, gs.Set, gName)
+	if aeroErr != nil {
+		return aeroErr
+	}
 
-	return merr.ErrorOrNil()
+	indexBits, minHashBits := gs.hllBits(gName)
+	ops := []*aero.Operation{
+		aero.MapPutItemsOp(aero.DefaultMapPolicy(), gBin, map[interface{}]interface{}{
+			indexBitsKey:   indexBits,
+			minHashBitsKey: minHashBits,
+		}),
+		aero.HLLInitOp(aero.DefaultHLLPolicy(), hllBin, indexBits, minHashBits),
+	}
+	if len(hfids) > 0 {
+		values := make([]aero.Value, len(hfids))
+		for i, n := range hfids {
+			values[i] = aero.NewLongValue(n)
+		}
+		ops = append(ops, aero.HLLAddOp(aero.DefaultHLLPolicy(), hllBin, values, indexBits, minHashBits))
+	}
+	_, err := gs.operate(ctx, key, ops...)
+	return err
 }
 
 func toString(any interface{}) (string, error) {
@@ -116,24 +342,42 @@ func toInt(any interface{}) (int, error) {
 	}
 }
 
-// Add Implemented using HLLAddOp command
-func (gs GeneratorStore) Add(_ context.Context, hfid int64, gName string) (bool, error) {
-	key, err := aero.NewKey(gs.Namespace, gs.Set, gName)
-	merr := multierror.Append(err)
+// lastResult returns the result of the last operation on a bin when multiple operations were done on the same bin
+func lastResult(r interface{}) interface{} {
+	if results, ok := r.([]interface{}); ok && len(results) > 0 {
+		return results[len(results)-1]
+	}
+	return r
+}
 
-	r, err := gs.Client.Operate(nil, key,
-		aero.HLLAddOp(aero.DefaultHLLPolicy(), hllBin,
-			[]aero.Value{aero.NewLongValue(hfid)}, 16, 4))
-	merr = multierror.Append(merr, err)
+// Add Implemented using HLLAddOp command. If the generator's Membership is a map or a set, the HFID is first added to
+// it and HLLAddOp command is only used to update the HFIDs count if the HFID was new.
+func (gs GeneratorStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	key, aeroErr := aero.NewKey(gs.Namespace, gs.Set, gName)
+	if aeroErr != nil {
+		return false, aeroErr
+	}
+
+	m := gs.membership(gName)
+	if m.Type != HyperLogLogMembership {
+		isNew, err := gs.addMember(ctx, m, hfid, gName)
+		if err != nil || !isNew {
+			return false, err
+		}
+	}
 
-	if merr.ErrorOrNil() != nil {
-		return false, merr.ErrorOrNil()
+	// The precision of the stored HLL is used since the configured one may have changed since it was created
+	r, err := gs.operate(ctx, key,
+		aero.HLLAddOp(aero.DefaultHLLPolicy(), hllBin,
+			[]aero.Value{aero.NewLongValue(hfid)}, existingBits, existingBits))
+	if err != nil {
+		return false, err
 	}
 
 	switch r.Bins[hllBin].(type) {
 	case int:
-		return r.Bins[hllBin].(int) > 0, merr.ErrorOrNil()
+		return m.Type != HyperLogLogMembership || r.Bins[hllBin].(int) > 0, nil
 	default:
-		return false, multierror.Append(merr, fmt.Errorf("hll Add Operation didn't return an int:  %s", r.Bins[hllBin])).ErrorOrNil()
+		return false, fmt.Errorf("hll Add Operation didn't return an int:  %s", r.Bins[hllBin])
 	}
 }
@@ -8,7 +8,9 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var aerospikeClient *aero.Client
@@ -143,8 +145,8 @@ func TestGeneratorStore_Upsert(t *testing.T) {
 		_, err = gs.Add(context.Background(), 12345, name)
 		assert.NoError(t, err)
 
-		// Upsert
-		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		// Upsert with a longer Length since shrinking the generator is rejected
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, existingLength+1)
 		assert.NoError(t, err)
 		err = gs.Upsert(context.Background(), *g)
 		assert.NoError(t, err)
@@ -156,6 +158,47 @@ func TestGeneratorStore_Upsert(t *testing.T) {
 		assert.Equal(t, int64(1), c)
 	})
 
+	t.Run("returns a ConflictError when a stale generator would shrink the stored Length", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		longerG := *g
+		longerG.Length++
+		assert.NoError(t, gs.Upsert(context.Background(), longerG))
+
+		err = gs.Upsert(context.Background(), *g)
+		var conflictErr hfid.ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, name, conflictErr.Name)
+
+		foundG, _, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, longerG, foundG)
+	})
+
+	t.Run("generates HFIDs concurrently without shrinking the generator", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, hfid.NumericEncoding, 1, 1)
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 25; j++ {
+					_, err := hfid.HFID(context.Background(), *g, gs)
+					assert.NoError(t, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		foundG, _, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(3), foundG.Length)
+	})
+
 	t.Run("returns an error when aerospike fails", func(t *testing.T) {
 		gs := prepareStore(t)
 		gs.Namespace = "invalid_namespace"
@@ -224,3 +267,186 @@ func TestGeneratorStore_Add(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestGeneratorStore_Context(t *testing.T) {
+	g, err := hfid.NewGenerator("test", "t-", hfid.DefaultEncoding, 1, 2)
+	assert.NoError(t, err)
+
+	t.Run("uses the default WritePolicy when no deadline is set", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.WritePolicy = aero.NewWritePolicy(0, 0)
+		gs.WritePolicy.TotalTimeout = time.Second
+
+		p, err := gs.writePolicy(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second, p.TotalTimeout)
+		assert.NotSame(t, gs.WritePolicy, p)
+	})
+
+	t.Run("caps the timeouts of the WritePolicy by the context deadline", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.WritePolicy = aero.NewWritePolicy(0, 0)
+		gs.WritePolicy.TotalTimeout = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		p, err := gs.writePolicy(ctx)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, p.TotalTimeout, time.Minute)
+		assert.LessOrEqual(t, p.SocketTimeout, time.Minute)
+		assert.Equal(t, time.Hour, gs.WritePolicy.TotalTimeout)
+	})
+
+	t.Run("fails when the context deadline is exceeded", func(t *testing.T) {
+		gs := prepareStore(t)
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		_, _, err := gs.InsertOrGet(ctx, *g)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, gs.Upsert(ctx, *g), context.DeadlineExceeded)
+		_, err = gs.Add(ctx, 0, g.Name)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("fails when the context is cancelled", func(t *testing.T) {
+		gs := prepareStore(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := gs.InsertOrGet(ctx, *g)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("works within the context deadline", func(t *testing.T) {
+		gs := prepareStore(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		foundG, c, err := gs.InsertOrGet(ctx, *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(0), c)
+	})
+}
+
+func TestGeneratorStore_hllBits(t *testing.T) {
+	tests := []struct {
+		name            string
+		expected        map[string]int64
+		maxErrorRate    float64
+		wantIndexBits   int
+		wantMinHashBits int
+	}{
+		{"defaults when the generator is not configured", nil, 0, 16, 4},
+		{"defaults when the expected cardinality is not positive", map[string]int64{"test": 0}, 0, 16, 4},
+		{"tiny generators keep the default error rate of 1%", map[string]int64{"test": 3}, 0, 14, 4},
+		{"small generators keep the default error rate of 1%", map[string]int64{"test": 1000}, 0, 14, 4},
+		{"index bits follow the error rate", map[string]int64{"test": 3}, 0.1, 7, 4},
+		{"index bits grow with the expected cardinality", map[string]int64{"test": 1000}, 0.1, 10, 4},
+		{"index bits don't go below 4", map[string]int64{"test": 3}, 0.5, 4, 4},
+		{"index bits don't go above 16", map[string]int64{"test": 1 << 40}, 0, 16, 4},
+		{"largest generators", map[string]int64{"test": 1<<63 - 1}, 0, 16, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := GeneratorStore{ExpectedCardinalities: tt.expected, MaxErrorRate: tt.maxErrorRate}
+			indexBits, minHashBits := gs.hllBits("test")
+			assert.Equal(t, tt.wantIndexBits, indexBits)
+			assert.Equal(t, tt.wantMinHashBits, minHashBits)
+		})
+	}
+}
+
+func TestGeneratorStore_ReinitializeHLL(t *testing.T) {
+	name := "test"
+	g, err := hfid.NewGenerator(name, "t-", hfid.DefaultEncoding, 1, 2)
+	assert.NoError(t, err)
+
+	describe := func(t *testing.T, gs GeneratorStore) (map[interface{}]interface{}, []interface{}) {
+		key, err := aero.NewKey(gs.Namespace, gs.Set, name)
+		assert.NoError(t, err)
+		r, err := gs.Client.Operate(nil, key, aero.GetBinOp(gBin), aero.HLLDescribeOp(hllBin))
+		assert.NoError(t, err)
+		return r.Bins[gBin].(map[interface{}]interface{}), r.Bins[hllBin].([]interface{})
+	}
+
+	t.Run("new generators use the precision chosen from the expected cardinality", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.ExpectedCardinalities = map[string]int64{name: 1000}
+		_, _, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+
+		storedG, hllDescription := describe(t, gs)
+		assert.Equal(t, 14, storedG[indexBitsKey])
+		assert.Equal(t, 4, storedG[minHashBitsKey])
+		assert.Equal(t, []interface{}{14, 4}, hllDescription)
+	})
+
+	t.Run("existing generators are reinitialized with the new precision", func(t *testing.T) {
+		gs := prepareStore(t)
+		_, _, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		_, err = gs.Add(context.Background(), 1, name)
+		assert.NoError(t, err)
+
+		gs.ExpectedCardinalities = map[string]int64{name: 1000}
+		assert.NoError(t, gs.ReinitializeHLL(context.Background(), name, 1, 2))
+
+		storedG, hllDescription := describe(t, gs)
+		assert.Equal(t, 14, storedG[indexBitsKey])
+		assert.Equal(t, []interface{}{14, 4}, hllDescription)
+		_, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), c)
+		isNew, err := gs.Add(context.Background(), 2, name)
+		assert.NoError(t, err)
+		assert.False(t, isNew)
+	})
+
+	t.Run("returns an error when aerospike fails", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.Namespace = "invalid_namespace"
+		assert.Error(t, gs.ReinitializeHLL(context.Background(), name))
+	})
+}
+
+func TestGeneratorStore_Memberships(t *testing.T) {
+	name := "test"
+	g, err := hfid.NewGenerator(name, "t-", hfid.DefaultEncoding, 1, 2)
+	assert.NoError(t, err)
+
+	for _, mt := range []MembershipType{MapMembership, SetMembership} {
+		t.Run(string(mt), func(t *testing.T) {
+			gs := prepareStore(t)
+			m := Membership{Type: mt, Set: gs.Set[:len(gs.Set)/2] + "-ids", Shards: 2}
+			_ = aerospikeClient.Truncate(nil, gs.Namespace, m.Set, nil)
+			gs.Memberships = map[string]Membership{name: m}
+			_, _, err := gs.InsertOrGet(context.Background(), *g)
+			assert.NoError(t, err)
+
+			for i := int64(0); i < 10; i++ {
+				isNew, err := gs.Add(context.Background(), i, name)
+				assert.NoError(t, err)
+				assert.True(t, isNew)
+			}
+			for i := int64(0); i < 10; i++ {
+				isNew, err := gs.Add(context.Background(), i, name)
+				assert.NoError(t, err)
+				assert.False(t, isNew)
+			}
+
+			_, c, err := gs.InsertOrGet(context.Background(), *g)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(10), c)
+		})
+	}
+
+	t.Run("returns an error when aerospike fails", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.Namespace = "invalid_namespace"
+		gs.Memberships = map[string]Membership{name: {Type: SetMembership}}
+		_, err := gs.Add(context.Background(), 0, name)
+		assert.Error(t, err)
+	})
+}
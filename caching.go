@@ -0,0 +1,118 @@
+package hfid
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CachingStore A GeneratorStore decorator that caches the generators along with an estimate number of their generated
+// HFIDs, so that HFID doesn't call InsertOrGet of the decorated store for every HFID. The estimate is increased locally
+// for every new HFID added through the CachingStore. A generator is fetched again from the decorated store once its
+// entry is older than TTL, or on every call once the estimate approaches the count at which the Length is increased.
+// Since CachingStore doesn't implement Claimer, HFID uses InsertOrGet, Upsert and Add even if the decorated store
+// implements Claimer. A CachingStore must not be copied after first use.
+type CachingStore struct {
+	GeneratorStore
+
+	// TTL is how long a generator is cached. 10 seconds is used when zero.
+	TTL time.Duration
+
+	// RefreshRatio is the ratio of the count at which the Length is increased after which the generator isn't served
+	// from the cache. 0.8 is used when zero.
+	RefreshRatio float64
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	g         Generator
+	count     int64
+	fetchedAt time.Time
+}
+
+// InsertOrGet returns the cached generator named g.Name and its estimate number of generated HFIDs, or calls
+// InsertOrGet of the decorated store if it isn't cached, it has expired or its Length is about to be increased.
+func (cs *CachingStore) InsertOrGet(ctx context.Context, g Generator) (Generator, int64, error) {
+	cs.mu.Lock()
+	e, ok := cs.entries[g.Name]
+	cs.mu.Unlock()
+	if ok && cs.timeNow().Sub(e.fetchedAt) < cs.ttl() && !cs.nearGrowth(e, g) {
+		return e.g, e.count, nil
+	}
+
+	storedG, c, err := cs.GeneratorStore.InsertOrGet(ctx, g)
+	if err != nil {
+		return storedG, c, err
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.entries == nil {
+		cs.entries = map[string]cacheEntry{}
+	}
+	cs.entries[g.Name] = cacheEntry{g: storedG, count: c, fetchedAt: cs.timeNow()}
+	return storedG, c, nil
+}
+
+// Upsert calls Upsert of the decorated store and removes the generator from the cache
+func (cs *CachingStore) Upsert(ctx context.Context, g Generator) error {
+	cs.Invalidate(g.Name)
+	return cs.GeneratorStore.Upsert(ctx, g)
+}
+
+// Add calls Add of the decorated store and increases the cached estimate if hfid was new
+func (cs *CachingStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	isNew, err := cs.GeneratorStore.Add(ctx, hfid, gName)
+	if err != nil || !isNew {
+		return isNew, err
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if e, ok := cs.entries[gName]; ok {
+		e.count++
+		cs.entries[gName] = e
+	}
+	return isNew, nil
+}
+
+// Invalidate removes the generator named gName from the cache so that it is fetched again by the next InsertOrGet
+func (cs *CachingStore) Invalidate(gName string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.entries, gName)
+}
+
+// nearGrowth returns true if the estimate of the entry has reached RefreshRatio of the count at which the Length is
+// increased. The partition and the template of g are used since stores don't persist them.
+func (cs *CachingStore) nearGrowth(e cacheEntry, g Generator) bool {
+	cachedG := e.g
+	cachedG.Partitions, cachedG.Partition, cachedG.Template = g.Partitions, g.Partition, g.Template
+	maxC, err := cachedG.countPartitionHFIDs()
+	if err != nil {
+		return true
+	}
+	return float64(e.count+1) > float64(maxC)*growthRatio*cs.refreshRatio()
+}
+
+func (cs *CachingStore) ttl() time.Duration {
+	if cs.TTL > 0 {
+		return cs.TTL
+	}
+	return 10 * time.Second
+}
+
+func (cs *CachingStore) refreshRatio() float64 {
+	if cs.RefreshRatio > 0 {
+		return cs.RefreshRatio
+	}
+	return 0.8
+}
+
+func (cs *CachingStore) timeNow() time.Time {
+	if cs.now != nil {
+		return cs.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,84 @@
+package hfid
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachingStore_InsertOrGet(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+
+	t.Run("Serves the generator from the cache until it expires", func(t *testing.T) {
+		now := time.Now()
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(3), nil).Twice()
+		mgs.On("Add", ctx, int64(7), g.Name).Return(true, nil).Once()
+		cs := &CachingStore{GeneratorStore: mgs, TTL: time.Minute, now: func() time.Time { return now }}
+
+		_, _, err := cs.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		_, err = cs.Add(ctx, 7, g.Name)
+		assert.NoError(t, err)
+		gotG, gotCount, err := cs.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, g, gotG)
+		assert.Equal(t, int64(4), gotCount, "new HFIDs are counted locally")
+
+		now = now.Add(time.Minute)
+		_, gotCount, err = cs.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), gotCount)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Fetches the generator on every call once its Length is about to be increased", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		// The Length is increased at 50 HFIDs and the generator is refreshed once 40 HFIDs have been generated
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(40), nil).Times(3)
+		cs := &CachingStore{GeneratorStore: mgs}
+
+		for i := 0; i < 3; i++ {
+			_, _, err := cs.InsertOrGet(ctx, g)
+			assert.NoError(t, err)
+		}
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Fetches the generator again after Upsert", func(t *testing.T) {
+		newG := g
+		newG.Length++
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil).Once()
+		mgs.On("Upsert", ctx, newG).Return(nil).Once()
+		mgs.On("InsertOrGet", ctx, g).Return(newG, int64(0), nil).Once()
+		cs := &CachingStore{GeneratorStore: mgs}
+
+		_, _, err := cs.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.NoError(t, cs.Upsert(ctx, newG))
+		gotG, _, err := cs.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, newG, gotG)
+	})
+}
+
+func TestCachingStore_HFID(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 3}
+	mcs := NewMockClaimerStore(t)
+	mcs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil).Once()
+	mcs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Times(10)
+	cs := &CachingStore{GeneratorStore: mcs}
+
+	for i := 0; i < 10; i++ {
+		_, err := HFID(ctx, g, cs)
+		assert.NoError(t, err)
+	}
+	mcs.AssertExpectations(t)
+	mcs.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,42 @@
+module gitlab.com/alielgamal/hfid/cmd/hfid-server
+
+go 1.19
+
+replace gitlab.com/alielgamal/hfid => ../..
+
+replace gitlab.com/alielgamal/hfid/redis => ../../redis
+
+replace gitlab.com/alielgamal/hfid/aerospike => ../../aerospike
+
+replace gitlab.com/alielgamal/hfid/grpc => ../../grpc
+
+require (
+	github.com/aerospike/aerospike-client-go/v6 v6.7.0
+	github.com/alicebob/miniredis/v2 v2.23.1
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/stretchr/testify v1.8.1
+	gitlab.com/alielgamal/hfid v0.0.0-20230102075629-28ea46d04362
+	gitlab.com/alielgamal/hfid/aerospike v0.0.0-00010101000000-000000000000
+	gitlab.com/alielgamal/hfid/grpc v0.0.0-00010101000000-000000000000
+	gitlab.com/alielgamal/hfid/redis v0.0.0-20230102072626-b1929db24d94
+	google.golang.org/grpc v1.52.0
+)
+
+require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/net v0.4.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.3.0 // indirect
+	golang.org/x/text v0.5.0 // indirect
+	google.golang.org/genproto v0.0.0-20221118155620-16455021b5e6 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
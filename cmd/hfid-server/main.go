@@ -0,0 +1,122 @@
+// Package main contains a standalone HTTP server that exposes HFID generation over a REST API using JSON bodies, so that
+// services that are not written in Go can generate HFIDs. The gRPC HFIDService can be served alongside it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	aero "github.com/aerospike/aerospike-client-go/v6"
+	"github.com/go-redis/redis/v8"
+	"gitlab.com/alielgamal/hfid"
+	hfidaero "gitlab.com/alielgamal/hfid/aerospike"
+	hfidgrpc "gitlab.com/alielgamal/hfid/grpc"
+	"gitlab.com/alielgamal/hfid/grpc/hfidpb"
+	hfidredis "gitlab.com/alielgamal/hfid/redis"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// generatorConfig An entry of the generator registry file
+type generatorConfig struct {
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+	Encoding  string `json:"encoding"`
+	MinLength uint8  `json:"minLength"`
+	Length    uint8  `json:"length"`
+
+	Partition  uint8 `json:"partition"`
+	Partitions uint8 `json:"partitions"`
+
+	Template string `json:"template"`
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	grpcAddr := flag.String("grpc-addr", "", "address to serve the gRPC HFIDService on (disabled when empty)")
+	generatorsFile := flag.String("generators", "generators.json", "path of the JSON file listing the generators")
+	maxBatch := flag.Int("max-batch", defaultMaxBatch, "maximum number of HFIDs generated or validated per request")
+	store := flag.String("store", "redis", "store backend to use: redis or aerospike")
+	redisAddrs := flag.String("redis-addrs", "localhost:6379", "comma separated addresses of the Redis nodes")
+	redisKeyPrefix := flag.String("redis-key-prefix", "", "prefix of the keys used by the Redis store")
+	aeroHost := flag.String("aerospike-host", "localhost", "host of an Aerospike node")
+	aeroPort := flag.Int("aerospike-port", 3000, "port of the Aerospike node")
+	aeroNamespace := flag.String("aerospike-namespace", "test", "Aerospike namespace of the generators")
+	aeroSet := flag.String("aerospike-set", "hfid", "Aerospike set of the generators")
+	flag.Parse()
+
+	generators, err := loadGenerators(*generatorsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var s hfid.GeneratorStore
+	switch *store {
+	case "redis":
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: strings.Split(*redisAddrs, ",")})
+		s = hfidredis.GeneratorStore{UniversalClient: uc, KeyPrefix: *redisKeyPrefix}
+	case "aerospike":
+		c, aeroErr := aero.NewClient(*aeroHost, *aeroPort)
+		if aeroErr != nil {
+			log.Fatal(aeroErr)
+		}
+		s = hfidaero.GeneratorStore{Client: c, Namespace: *aeroNamespace, Set: *aeroSet}
+	default:
+		log.Fatalf("unknown store '%s'", *store)
+	}
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gs := grpc.NewServer()
+		hfidpb.RegisterHFIDServiceServer(gs, hfidgrpc.Server{Store: s, Generators: generators, MaxBatch: *maxBatch})
+		log.Printf("Serving gRPC on %s", *grpcAddr)
+		go func() { log.Fatal(gs.Serve(lis)) }()
+	}
+
+	log.Printf("Serving %d generators on %s", len(generators), *addr)
+	log.Fatal(http.ListenAndServe(*addr, Server{Store: s, Generators: generators, MaxBatch: *maxBatch}))
+}
+
+// loadGenerators reads the generator registry file and validates every generator in it
+func loadGenerators(path string) (map[string]hfid.Generator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var configs []generatorConfig
+	if err := json.NewDecoder(f).Decode(&configs); err != nil {
+		return nil, fmt.Errorf("invalid generators file '%s': %s", path, err)
+	}
+
+	result := make(map[string]hfid.Generator, len(configs))
+	for _, c := range configs {
+		e := hfid.Encoding(c.Encoding)
+		if e == "" {
+			e = hfid.DefaultEncoding
+		}
+		g, err := hfid.NewGenerator(c.Name, c.Prefix, e, c.MinLength, c.Length)
+		if err == nil {
+			g, err = g.WithPartition(c.Partition, c.Partitions)
+		}
+		if err == nil && c.Template != "" {
+			g, err = g.WithTemplate(c.Template)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid generator '%s': %s", c.Name, err)
+		}
+		if _, ok := result[g.Name]; ok {
+			return nil, fmt.Errorf("duplicate generator '%s'", g.Name)
+		}
+		result[g.Name] = *g
+	}
+	return result, nil
+}
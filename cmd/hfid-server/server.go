@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gitlab.com/alielgamal/hfid"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const defaultMaxBatch = 1000
+
+// Server An http.Handler that exposes HFID generation over a REST API using JSON bodies. Only the generators found in
+// the Generators registry can be used, keyed by their name.
+type Server struct {
+	Store      hfid.GeneratorStore
+	Generators map[string]hfid.Generator
+
+	// MaxBatch is the maximum number of HFIDs that can be generated or validated in a single request. 1000 is used when
+	// zero.
+	MaxBatch int
+}
+
+// generateRequest The body of POST /generators/{name}/ids. The body is optional and a single HFID is generated if it
+// is missing.
+type generateRequest struct {
+	Count int `json:"count"`
+}
+
+type generateResponse struct {
+	IDs []string `json:"ids"`
+}
+
+type generatorResponse struct {
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+	Encoding  string `json:"encoding"`
+	MinLength uint8  `json:"minLength"`
+	Length    uint8  `json:"length"`
+	Count     int64  `json:"count"`
+}
+
+type listGeneratorsResponse struct {
+	Generators []generatorResponse `json:"generators"`
+}
+
+// validateRequest The body of POST /ids/validate. Either ID or IDs must be set.
+type validateRequest struct {
+	Generator string   `json:"generator"`
+	ID        string   `json:"id,omitempty"`
+	IDs       []string `json:"ids,omitempty"`
+}
+
+type validationResult struct {
+	ID     string `json:"id"`
+	Valid  bool   `json:"valid"`
+	Number *int64 `json:"number,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type validateResponse struct {
+	Results []validationResult `json:"results"`
+}
+
+// apiError The structured error returned in the body of every failed request
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e apiError) Error() string {
+	return e.Message
+}
+
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var result interface{}
+	var err error
+	switch {
+	case len(segments) == 1 && segments[0] == "generators":
+		if err = allowMethod(r, http.MethodGet); err == nil {
+			result, err = s.listGenerators(r)
+		}
+	case len(segments) == 3 && segments[0] == "generators" && segments[2] == "ids":
+		if err = allowMethod(r, http.MethodPost); err == nil {
+			result, err = s.generate(r, segments[1])
+		}
+	case len(segments) == 2 && segments[0] == "ids" && segments[1] == "validate":
+		if err = allowMethod(r, http.MethodPost); err == nil {
+			result, err = s.validate(r)
+		}
+	default:
+		err = apiError{http.StatusNotFound, "not_found", fmt.Sprintf("path '%s' not found", r.URL.Path)}
+	}
+
+	if err != nil {
+		var ae apiError
+		switch {
+		case errors.As(err, &ae):
+		case errors.Is(err, hfid.ErrRateLimited):
+			ae = apiError{http.StatusTooManyRequests, "rate_limited", err.Error()}
+		default:
+			ae = apiError{http.StatusInternalServerError, "internal", err.Error()}
+		}
+		writeJSON(w, ae.Status, errorResponse{ae})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s Server) listGenerators(r *http.Request) (listGeneratorsResponse, error) {
+	names := make([]string, 0, len(s.Generators))
+	for name := range s.Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := listGeneratorsResponse{Generators: make([]generatorResponse, 0, len(names))}
+	for _, name := range names {
+		g, c, err := s.storedGenerator(r.Context(), s.Generators[name])
+		if err != nil {
+			return result, err
+		}
+		result.Generators = append(result.Generators, generatorResponse{
+			Name:      g.Name,
+			Prefix:    g.Prefix,
+			Encoding:  string(g.Encoding),
+			MinLength: g.MinLength,
+			Length:    g.Length,
+			Count:     c,
+		})
+	}
+	return result, nil
+}
+
+func (s Server) generate(r *http.Request, name string) (generateResponse, error) {
+	g, err := s.generator(name)
+	if err != nil {
+		return generateResponse{}, err
+	}
+
+	req := generateRequest{Count: 1}
+	if r.ContentLength != 0 {
+		if err := decodeBody(r, &req); err != nil {
+			return generateResponse{}, err
+		}
+	}
+	if req.Count < 1 || req.Count > s.maxBatch() {
+		return generateResponse{}, apiError{http.StatusBadRequest, "invalid_count",
+			fmt.Sprintf("count must be between 1 and %d", s.maxBatch())}
+	}
+
+	result := generateResponse{IDs: make([]string, 0, req.Count)}
+	for i := 0; i < req.Count; i++ {
+		id, err := hfid.HFID(r.Context(), g, s.Store)
+		if err != nil {
+			return result, err
+		}
+		result.IDs = append(result.IDs, id)
+	}
+	return result, nil
+}
+
+func (s Server) validate(r *http.Request) (validateResponse, error) {
+	var req validateRequest
+	if err := decodeBody(r, &req); err != nil {
+		return validateResponse{}, err
+	}
+	g, err := s.generator(req.Generator)
+	if err != nil {
+		return validateResponse{}, err
+	}
+
+	ids := req.IDs
+	if req.ID != "" {
+		ids = append([]string{req.ID}, ids...)
+	}
+	if len(ids) == 0 || len(ids) > s.maxBatch() {
+		return validateResponse{}, apiError{http.StatusBadRequest, "invalid_count",
+			fmt.Sprintf("between 1 and %d ids must be provided", s.maxBatch())}
+	}
+
+	// Validate against the stored Length since the generator may have grown since it was registered, and against the
+	// registered partition and template since stores don't persist them
+	storedG, _, err := s.storedGenerator(r.Context(), g)
+	if err != nil {
+		return validateResponse{}, err
+	}
+	storedG.Partitions, storedG.Partition, storedG.Template = g.Partitions, g.Partition, g.Template
+	g = storedG
+
+	result := validateResponse{Results: make([]validationResult, 0, len(ids))}
+	for _, id := range ids {
+		n, err := hfid.Parse(g, id)
+		if err != nil {
+			result.Results = append(result.Results, validationResult{ID: id, Error: err.Error()})
+			continue
+		}
+		result.Results = append(result.Results, validationResult{ID: id, Valid: true, Number: &n})
+	}
+	return result, nil
+}
+
+func (s Server) generator(name string) (hfid.Generator, error) {
+	g, ok := s.Generators[name]
+	if !ok {
+		return g, apiError{http.StatusNotFound, "generator_not_found", fmt.Sprintf("generator '%s' not found", name)}
+	}
+	return g, nil
+}
+
+// storedGenerator returns the stored version of the registered generator g along with the estimate number of HFIDs
+// generated using it. The generator is fetched without being created when the store implements hfid.GeneratorAdmin, in
+// which case g is returned as is if it hasn't been stored yet.
+func (s Server) storedGenerator(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	admin, ok := s.Store.(hfid.GeneratorAdmin)
+	if !ok {
+		return s.Store.InsertOrGet(ctx, g)
+	}
+	storedG, c, found, err := admin.Get(ctx, g.Name)
+	if err != nil || !found {
+		return g, 0, err
+	}
+	return storedG, c, nil
+}
+
+func (s Server) maxBatch() int {
+	if s.MaxBatch > 0 {
+		return s.MaxBatch
+	}
+	return defaultMaxBatch
+}
+
+func allowMethod(r *http.Request, method string) error {
+	if r.Method != method {
+		return apiError{http.StatusMethodNotAllowed, "method_not_allowed",
+			fmt.Sprintf("method '%s' is not allowed, use '%s'", r.Method, method)}
+	}
+	return nil
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return apiError{http.StatusBadRequest, "invalid_body", fmt.Sprintf("invalid request body: %s", err)}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	hfidredis "gitlab.com/alielgamal/hfid/redis"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestServer returns a Server backed by miniredis. The HFIDs of User are tracked using a Set since the HyperLogLog
+// may rarely report a duplicate HFID as new.
+func newTestServer(t *testing.T) Server {
+	mr := miniredis.RunT(t)
+	uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	return Server{
+		Store: hfidredis.GeneratorStore{
+			UniversalClient: uc,
+			Memberships:     map[string]hfidredis.Membership{"User": {Type: hfidredis.SetMembership}},
+		},
+		Generators: map[string]hfid.Generator{
+			"User":  {Name: "User", Prefix: "U-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 3},
+			"Order": {Name: "Order", Prefix: "O-", Encoding: hfid.DefaultEncoding, MinLength: 1, Length: 2},
+		},
+		MaxBatch: 10,
+	}
+}
+
+// rateLimitedStore A GeneratorStore that fails as if every generator was rate limited
+type rateLimitedStore struct {
+	hfid.GeneratorStore
+}
+
+func (rateLimitedStore) InsertOrGet(_ context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	return g, 0, fmt.Errorf("generator '%s' is %w", g.Name, hfid.ErrRateLimited)
+}
+
+func serve(s Server, method string, path string, body string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var result map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	return w.Code, result
+}
+
+func TestServer_generate(t *testing.T) {
+	t.Run("Generates a single HFID when the body is missing", func(t *testing.T) {
+		status, body := serve(newTestServer(t), http.MethodPost, "/generators/User/ids", "")
+		assert.Equal(t, http.StatusOK, status)
+		ids := body["ids"].([]interface{})
+		assert.Len(t, ids, 1)
+		assert.Regexp(t, "^U-[0-9]{3}$", ids[0])
+	})
+
+	t.Run("Generates a batch of unique HFIDs", func(t *testing.T) {
+		status, body := serve(newTestServer(t), http.MethodPost, "/generators/User/ids", `{"count": 10}`)
+		assert.Equal(t, http.StatusOK, status)
+		ids := body["ids"].([]interface{})
+		assert.Len(t, ids, 10)
+		unique := map[interface{}]bool{}
+		for _, id := range ids {
+			unique[id] = true
+		}
+		assert.Len(t, unique, 10)
+	})
+
+	t.Run("Fails with Too Many Requests if the generator is rate limited", func(t *testing.T) {
+		s := newTestServer(t)
+		s.Store = rateLimitedStore{s.Store}
+		status, body := serve(s, http.MethodPost, "/generators/User/ids", "")
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, "rate_limited", body["error"].(map[string]interface{})["code"])
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"Fails if the generator is not registered", http.MethodPost, "/generators/Unknown/ids", "", http.StatusNotFound, "generator_not_found"},
+		{"Fails if the count exceeds the maximum batch", http.MethodPost, "/generators/User/ids", `{"count": 11}`, http.StatusBadRequest, "invalid_count"},
+		{"Fails if the count is not positive", http.MethodPost, "/generators/User/ids", `{"count": 0}`, http.StatusBadRequest, "invalid_count"},
+		{"Fails if the body is invalid", http.MethodPost, "/generators/User/ids", `{"number": 1}`, http.StatusBadRequest, "invalid_body"},
+		{"Fails if the method is not POST", http.MethodGet, "/generators/User/ids", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"Fails if the path is unknown", http.MethodPost, "/users", "", http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(newTestServer(t), tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantCode, body["error"].(map[string]interface{})["code"])
+		})
+	}
+}
+
+func TestServer_listGenerators(t *testing.T) {
+	s := newTestServer(t)
+	serve(s, http.MethodPost, "/generators/User/ids", `{"count": 2}`)
+
+	status, body := serve(s, http.MethodGet, "/generators", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "Order", "prefix": "O-", "encoding": hfid.DefaultEncoding, "minLength": float64(1), "length": float64(2), "count": float64(0)},
+		map[string]interface{}{"name": "User", "prefix": "U-", "encoding": hfid.NumericEncoding, "minLength": float64(1), "length": float64(3), "count": float64(2)},
+	}, body["generators"])
+
+	names, err := s.Store.(hfid.GeneratorAdmin).List(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"User"}, names, "listing the generators must not create them")
+}
+
+func TestServer_validate(t *testing.T) {
+	t.Run("Validates a batch of HFIDs", func(t *testing.T) {
+		status, body := serve(newTestServer(t), http.MethodPost, "/ids/validate",
+			`{"generator": "User", "id": "U-042", "ids": ["O-042", "U-0042"]}`)
+		assert.Equal(t, http.StatusOK, status)
+		results := body["results"].([]interface{})
+		assert.Len(t, results, 3)
+		assert.Equal(t, map[string]interface{}{"id": "U-042", "valid": true, "number": float64(42)}, results[0])
+		assert.Equal(t, false, results[1].(map[string]interface{})["valid"])
+		assert.NotEmpty(t, results[1].(map[string]interface{})["error"])
+		assert.Equal(t, false, results[2].(map[string]interface{})["valid"])
+	})
+
+	t.Run("Fails if no HFIDs are provided", func(t *testing.T) {
+		status, body := serve(newTestServer(t), http.MethodPost, "/ids/validate", `{"generator": "User"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_count", body["error"].(map[string]interface{})["code"])
+	})
+
+	t.Run("Validates HFIDs of generators that haven't been stored yet without creating them", func(t *testing.T) {
+		s := newTestServer(t)
+		status, body := serve(s, http.MethodPost, "/ids/validate", `{"generator": "Order", "id": "O-0A"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, true, body["results"].([]interface{})[0].(map[string]interface{})["valid"])
+
+		_, _, found, err := s.Store.(hfid.GeneratorAdmin).Get(context.Background(), "Order")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Fails if the generator is not registered", func(t *testing.T) {
+		status, body := serve(newTestServer(t), http.MethodPost, "/ids/validate", `{"generator": "Unknown", "id": "U-1"}`)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "generator_not_found", body["error"].(map[string]interface{})["code"])
+	})
+}
+
+func Test_loadGenerators(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "generators.json")
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	t.Run("Loads the generators and defaults the encoding", func(t *testing.T) {
+		generators, err := loadGenerators(write(t, `[{"name": "User", "prefix": "U-", "minLength": 1, "length": 2}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]hfid.Generator{
+			"User": {Name: "User", Prefix: "U-", Encoding: hfid.DefaultEncoding, MinLength: 1, Length: 2},
+		}, generators)
+	})
+
+	t.Run("Fails if a generator is invalid", func(t *testing.T) {
+		_, err := loadGenerators(write(t, `[{"name": "User", "length": 0}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Loads partitioned generators", func(t *testing.T) {
+		generators, err := loadGenerators(write(t, `[{"name": "User", "length": 2, "partition": 1, "partitions": 3}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(1), generators["User"].Partition)
+		assert.Equal(t, uint8(3), generators["User"].Partitions)
+
+		_, err = loadGenerators(write(t, `[{"name": "User", "length": 2, "partition": 3, "partitions": 3}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Loads templated generators", func(t *testing.T) {
+		generators, err := loadGenerators(write(t, `[{"name": "User", "length": 2, "template": "AAA-999"}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, "AAA-999", generators["User"].Template)
+
+		_, err = loadGenerators(write(t, `[{"name": "User", "length": 2, "template": "A"}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Fails if a generator is duplicated", func(t *testing.T) {
+		_, err := loadGenerators(write(t, `[{"name": "User", "length": 1}, {"name": "User", "length": 2}]`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gitlab.com/alielgamal/hfid"
+	"io"
+	"text/tabwriter"
+)
+
+// cli Runs the commands against the store and prints their output as text or as JSON
+type cli struct {
+	store hfid.GeneratorStore
+	out   io.Writer
+	json  bool
+}
+
+type generatorOutput struct {
+	Name      string  `json:"name"`
+	Prefix    string  `json:"prefix"`
+	Encoding  string  `json:"encoding"`
+	MinLength uint8   `json:"minLength"`
+	Length    uint8   `json:"length"`
+	Count     int64   `json:"count"`
+	Capacity  int64   `json:"capacity"`
+	FillRatio float64 `json:"fillRatio"`
+}
+
+type validationOutput struct {
+	ID     string `json:"id"`
+	Valid  bool   `json:"valid"`
+	Number *int64 `json:"number,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (c cli) run(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "generate":
+		return c.generate(ctx, args[1:])
+	case "parse":
+		return c.parse(ctx, args[1:])
+	case "validate":
+		return c.validate(ctx, args[1:])
+	case "stats":
+		return c.stats(ctx, args[1:])
+	case "generator":
+		if len(args) < 2 {
+			return fmt.Errorf("missing generator command: create, show, list, grow or delete")
+		}
+		switch args[1] {
+		case "create":
+			return c.create(ctx, args[2:])
+		case "show":
+			return c.show(ctx, args[2:])
+		case "list":
+			return c.list(ctx)
+		case "grow":
+			return c.grow(ctx, args[2:])
+		case "delete":
+			return c.delete(ctx, args[2:])
+		default:
+			return fmt.Errorf("unknown generator command '%s'", args[1])
+		}
+	default:
+		return fmt.Errorf("unknown command '%s'", args[0])
+	}
+}
+
+func (c cli) generate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	count := fs.Int("count", 1, "number of HFIDs to generate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *count < 1 {
+		return fmt.Errorf("usage: generate [-count n] <generator>")
+	}
+	g, _, err := c.get(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, *count)
+	for i := 0; i < *count; i++ {
+		id, err := hfid.HFID(ctx, g, c.store)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	if c.json {
+		return c.printJSON(map[string][]string{"ids": ids})
+	}
+	for _, id := range ids {
+		fmt.Fprintln(c.out, id)
+	}
+	return nil
+}
+
+func (c cli) parse(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: parse <generator> <hfid>")
+	}
+	g, _, err := c.get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	n, err := hfid.Parse(g, args[1])
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(validationOutput{ID: args[1], Valid: true, Number: &n})
+	}
+	fmt.Fprintln(c.out, n)
+	return nil
+}
+
+func (c cli) validate(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: validate <generator> <hfid>...")
+	}
+	g, _, err := c.get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	results := make([]validationOutput, 0, len(args)-1)
+	invalid := 0
+	for _, id := range args[1:] {
+		n, err := hfid.Parse(g, id)
+		if err != nil {
+			invalid++
+			results = append(results, validationOutput{ID: id, Error: err.Error()})
+			continue
+		}
+		results = append(results, validationOutput{ID: id, Valid: true, Number: &n})
+	}
+
+	if c.json {
+		err = c.printJSON(map[string][]validationOutput{"results": results})
+	} else {
+		for _, r := range results {
+			if r.Valid {
+				fmt.Fprintf(c.out, "%s\tvalid\n", r.ID)
+			} else {
+				fmt.Fprintf(c.out, "%s\tinvalid: %s\n", r.ID, r.Error)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d HFIDs are invalid", invalid, len(results))
+	}
+	return nil
+}
+
+func (c cli) create(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("generator create", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "prefix of the HFIDs")
+	encoding := fs.String("encoding", hfid.DefaultEncoding, "characters used to encode the HFIDs")
+	minLength := fs.Uint("min-length", 1, "minimum length of the HFIDs")
+	length := fs.Uint("length", 1, "initial length of the HFIDs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: generator create [-prefix p] [-encoding e] [-min-length n] [-length n] <generator>")
+	}
+	if *minLength > 255 || *length > 255 {
+		return fmt.Errorf("min-length and length cannot be greater than 255")
+	}
+	g, err := hfid.NewGenerator(fs.Arg(0), *prefix, hfid.Encoding(*encoding), uint8(*minLength), uint8(*length))
+	if err != nil {
+		return err
+	}
+
+	admin, err := c.admin()
+	if err != nil {
+		return err
+	}
+	_, _, found, err := admin.Get(ctx, g.Name)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("generator '%s' already exists", g.Name)
+	}
+	if err := c.store.Upsert(ctx, *g); err != nil {
+		return err
+	}
+	return c.printGenerators(newGeneratorOutput(*g, 0))
+}
+
+func (c cli) show(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: generator show <generator>")
+	}
+	g, count, err := c.get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	out := newGeneratorOutput(g, count)
+	if c.json {
+		return c.printJSON(out)
+	}
+	fmt.Fprintf(c.out, "Name:       %s\nPrefix:     %s\nEncoding:   %s\nMin Length: %d\nLength:     %d\nCount:      %d\n",
+		out.Name, out.Prefix, out.Encoding, out.MinLength, out.Length, out.Count)
+	return nil
+}
+
+func (c cli) list(ctx context.Context) error {
+	admin, err := c.admin()
+	if err != nil {
+		return err
+	}
+	names, err := admin.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		if names == nil {
+			names = []string{}
+		}
+		return c.printJSON(map[string][]string{"generators": names})
+	}
+	for _, name := range names {
+		fmt.Fprintln(c.out, name)
+	}
+	return nil
+}
+
+func (c cli) grow(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("generator grow", flag.ContinueOnError)
+	by := fs.Uint("by", 1, "number of characters to add to the length")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: generator grow [-by n] <generator>")
+	}
+	g, count, err := c.get(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *by == 0 || uint(g.Length)+*by > 255 {
+		return fmt.Errorf("by must be between 1 and %d", 255-g.Length)
+	}
+	grownG, err := hfid.NewGenerator(g.Name, g.Prefix, g.Encoding, g.MinLength, g.Length+uint8(*by))
+	if err != nil {
+		return err
+	}
+	if err := c.store.Upsert(ctx, *grownG); err != nil {
+		return err
+	}
+	return c.printGenerators(newGeneratorOutput(*grownG, count))
+}
+
+func (c cli) delete(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("generator delete", flag.ContinueOnError)
+	force := fs.Bool("force", false, "confirm the deletion. HFIDs generated before may be generated again")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: generator delete -force <generator>")
+	}
+	if !*force {
+		return fmt.Errorf("deleting generator '%s' allows its HFIDs to be generated again, pass -force to confirm", fs.Arg(0))
+	}
+	if _, _, err := c.get(ctx, fs.Arg(0)); err != nil {
+		return err
+	}
+	admin, err := c.admin()
+	if err != nil {
+		return err
+	}
+	return admin.Delete(ctx, fs.Arg(0))
+}
+
+func (c cli) stats(ctx context.Context, args []string) error {
+	names := args
+	if len(names) == 0 {
+		admin, err := c.admin()
+		if err != nil {
+			return err
+		}
+		if names, err = admin.List(ctx); err != nil {
+			return err
+		}
+	}
+
+	outs := make([]generatorOutput, 0, len(names))
+	for _, name := range names {
+		g, count, err := c.get(ctx, name)
+		if err != nil {
+			return err
+		}
+		outs = append(outs, newGeneratorOutput(g, count))
+	}
+	return c.printGenerators(outs...)
+}
+
+// get fetches the generator named gName without creating it
+func (c cli) get(ctx context.Context, gName string) (hfid.Generator, int64, error) {
+	admin, err := c.admin()
+	if err != nil {
+		return hfid.Generator{}, 0, err
+	}
+	g, count, found, err := admin.Get(ctx, gName)
+	if err != nil {
+		return g, 0, err
+	}
+	if !found {
+		return g, 0, fmt.Errorf("generator '%s' not found", gName)
+	}
+	return g, count, nil
+}
+
+func (c cli) admin() (hfid.GeneratorAdmin, error) {
+	admin, ok := c.store.(hfid.GeneratorAdmin)
+	if !ok {
+		return nil, fmt.Errorf("the store doesn't support managing generators")
+	}
+	return admin, nil
+}
+
+func newGeneratorOutput(g hfid.Generator, count int64) generatorOutput {
+	capacity := int64(1)
+	for i := 0; i < int(g.Length); i++ {
+		capacity *= int64(len(g.Encoding))
+	}
+	return generatorOutput{
+		Name:      g.Name,
+		Prefix:    g.Prefix,
+		Encoding:  string(g.Encoding),
+		MinLength: g.MinLength,
+		Length:    g.Length,
+		Count:     count,
+		Capacity:  capacity,
+		FillRatio: float64(count) / float64(capacity),
+	}
+}
+
+// printGenerators prints the generators as a table or as a JSON array
+func (c cli) printGenerators(outs ...generatorOutput) error {
+	if c.json {
+		return c.printJSON(map[string][]generatorOutput{"generators": outs})
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPREFIX\tLENGTH\tCOUNT\tCAPACITY\tFILL")
+	for _, o := range outs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.1f%%\n", o.Name, o.Prefix, o.Length, o.Count, o.Capacity, o.FillRatio*100)
+	}
+	return w.Flush()
+}
+
+func (c cli) printJSON(v interface{}) error {
+	e := json.NewEncoder(c.out)
+	e.SetIndent("", "  ")
+	return e.Encode(v)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+// runWith runs the CLI against miniredis and returns the printed output
+func runWith(mr *miniredis.Miniredis, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := run(context.Background(), append([]string{"-redis-addrs", mr.Addr()}, args...), &out, io.Discard)
+	return out.String(), err
+}
+
+func TestCLI_generator(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	_, err := runWith(mr, "generator", "create", "-prefix", "U-", "-encoding", "0123456789", "-length", "2", "User")
+	assert.NoError(t, err)
+
+	t.Run("Fails to create a generator that already exists", func(t *testing.T) {
+		_, err := runWith(mr, "generator", "create", "User")
+		assert.EqualError(t, err, "generator 'User' already exists")
+	})
+
+	t.Run("Shows the generator as JSON", func(t *testing.T) {
+		out, err := runWith(mr, "-json", "generator", "show", "User")
+		assert.NoError(t, err)
+		var g generatorOutput
+		assert.NoError(t, json.Unmarshal([]byte(out), &g))
+		assert.Equal(t, generatorOutput{Name: "User", Prefix: "U-", Encoding: "0123456789", MinLength: 1, Length: 2, Capacity: 100}, g)
+	})
+
+	t.Run("Fails to show a generator that doesn't exist without creating it", func(t *testing.T) {
+		_, err := runWith(mr, "generator", "show", "Missing")
+		assert.EqualError(t, err, "generator 'Missing' not found")
+		assert.False(t, mr.Exists("Missing"))
+	})
+
+	t.Run("Lists the generators", func(t *testing.T) {
+		out, err := runWith(mr, "generator", "list")
+		assert.NoError(t, err)
+		assert.Equal(t, "User\n", out)
+	})
+
+	t.Run("Grows the generator", func(t *testing.T) {
+		_, err := runWith(mr, "generator", "grow", "-by", "2", "User")
+		assert.NoError(t, err)
+		out, err := runWith(mr, "generator", "show", "User")
+		assert.NoError(t, err)
+		assert.Contains(t, out, "Length:     4\n")
+	})
+
+	t.Run("Requires -force to delete the generator", func(t *testing.T) {
+		_, err := runWith(mr, "generator", "delete", "User")
+		assert.Error(t, err)
+
+		_, err = runWith(mr, "generator", "delete", "-force", "User")
+		assert.NoError(t, err)
+		out, err := runWith(mr, "-json", "generator", "list")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"generators": []}`, out)
+	})
+}
+
+func TestCLI_generate(t *testing.T) {
+	mr := miniredis.RunT(t)
+	_, err := runWith(mr, "generator", "create", "-prefix", "U-", "-encoding", "0123456789", "-length", "3", "User")
+	assert.NoError(t, err)
+
+	out, err := runWith(mr, "generate", "-count", "5", "User")
+	assert.NoError(t, err)
+	ids := strings.Fields(out)
+	assert.Len(t, ids, 5)
+	for _, id := range ids {
+		assert.Regexp(t, "^U-[0-9]{3}$", id)
+	}
+
+	t.Run("Parses a generated HFID", func(t *testing.T) {
+		out, err := runWith(mr, "parse", "User", "U-042")
+		assert.NoError(t, err)
+		assert.Equal(t, "42\n", out)
+	})
+
+	t.Run("Validates HFIDs and fails if any is invalid", func(t *testing.T) {
+		out, err := runWith(mr, "-json", "validate", "User", ids[0], "X-042")
+		assert.EqualError(t, err, "1 of 2 HFIDs are invalid")
+		var results map[string][]validationOutput
+		assert.NoError(t, json.Unmarshal([]byte(out), &results))
+		assert.True(t, results["results"][0].Valid)
+		assert.False(t, results["results"][1].Valid)
+	})
+
+	t.Run("Shows the stats of all the generators", func(t *testing.T) {
+		out, err := runWith(mr, "-json", "stats")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"generators": [{"name": "User", "prefix": "U-", "encoding": "0123456789", "minLength": 1,
+			"length": 3, "count": 5, "capacity": 1000, "fillRatio": 0.005}]}`, out)
+	})
+
+	t.Run("Fails to generate HFIDs for a generator that doesn't exist", func(t *testing.T) {
+		_, err := runWith(mr, "generate", "Missing")
+		assert.EqualError(t, err, "generator 'Missing' not found")
+	})
+}
+
+func TestCLI_run(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"Fails without a command", nil, "missing command"},
+		{"Fails with an unknown command", []string{"unknown"}, "unknown command 'unknown'"},
+		{"Fails with an unknown generator command", []string{"generator", "rename"}, "unknown generator command 'rename'"},
+		{"Fails with an unknown store", []string{"-store", "unknown", "generator", "list"}, "unknown store 'unknown'"},
+		{"Fails to generate a negative count of HFIDs", []string{"generate", "-count", "-1", "User"}, "usage: generate [-count n] <generator>"},
+		{"Fails to generate zero HFIDs", []string{"generate", "-count", "0", "User"}, "usage: generate [-count n] <generator>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runWith(mr, tt.args...)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
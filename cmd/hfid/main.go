@@ -0,0 +1,86 @@
+// Package main contains a command-line tool for operators to generate and parse HFIDs and to manage the generators
+// stored in Redis or Aerospike.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	aero "github.com/aerospike/aerospike-client-go/v6"
+	"github.com/go-redis/redis/v8"
+	"gitlab.com/alielgamal/hfid"
+	hfidaero "gitlab.com/alielgamal/hfid/aerospike"
+	hfidredis "gitlab.com/alielgamal/hfid/redis"
+	"io"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: hfid [flags] <command> [command flags] [args]
+
+Commands:
+  generate [-count n] <generator>          generate HFIDs
+  parse <generator> <hfid>                 decode an HFID into the number it encodes
+  validate <generator> <hfid>...           check that HFIDs can have been generated by the generator
+  generator create [flags] <generator>     create a generator
+  generator show <generator>               show a generator
+  generator list                           list the generators
+  generator grow [-by n] <generator>       increase the length of a generator
+  generator delete -force <generator>      delete a generator
+  stats [generator]...                     show the usage of the generators (all generators by default)
+
+Flags:
+`
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags, connects to the store and runs the command found in args
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("hfid", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	jsonOutput := fs.Bool("json", false, "print the output as JSON")
+	store := fs.String("store", "redis", "store backend to use: redis or aerospike")
+	redisAddrs := fs.String("redis-addrs", "localhost:6379", "comma separated addresses of the Redis nodes")
+	redisKeyPrefix := fs.String("redis-key-prefix", "", "prefix of the keys used by the Redis store")
+	redisHashTag := fs.Bool("redis-hash-tag", false, "wrap the generator names in the Redis keys with {}")
+	aeroHost := fs.String("aerospike-host", "localhost", "host of an Aerospike node")
+	aeroPort := fs.Int("aerospike-port", 3000, "port of the Aerospike node")
+	aeroNamespace := fs.String("aerospike-namespace", "test", "Aerospike namespace of the generators")
+	aeroSet := fs.String("aerospike-set", "hfid", "Aerospike set of the generators")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	var s hfid.GeneratorStore
+	switch *store {
+	case "redis":
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: strings.Split(*redisAddrs, ",")})
+		defer uc.Close()
+		s = hfidredis.GeneratorStore{UniversalClient: uc, KeyPrefix: *redisKeyPrefix, HashTag: *redisHashTag}
+	case "aerospike":
+		c, aeroErr := aero.NewClient(*aeroHost, *aeroPort)
+		if aeroErr != nil {
+			return aeroErr
+		}
+		defer c.Close()
+		s = hfidaero.GeneratorStore{Client: c, Namespace: *aeroNamespace, Set: *aeroSet}
+	default:
+		return fmt.Errorf("unknown store '%s'", *store)
+	}
+
+	c := cli{store: s, out: stdout, json: *jsonOutput}
+	return c.run(ctx, fs.Args())
+}
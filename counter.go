@@ -0,0 +1,110 @@
+package hfid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Counter An optional interface that a GeneratorStore can implement to generate HFIDs using a CounterSource
+type Counter interface {
+	// Increment atomically increments the counter of the generator named gName and returns its value before the
+	// increment, starting from 0.
+	Increment(ctx context.Context, gName string) (int64, error)
+}
+
+// CounterStore A GeneratorStore that implements Counter
+type CounterStore interface {
+	GeneratorStore
+	Counter
+}
+
+// CounterSource A Source that generates HFIDs using the counter of the generator instead of drawing random HFIDs. The
+// counter is passed through the Permutation before being encoded, hence the HFIDs are unique without any collision
+// retries and they don't reveal the number of generated HFIDs without the key of the Permutation. Each HFID costs a
+// single Increment call besides InsertOrGet. The Length of the generator is increased when the counter reaches 50% of
+// the HFIDs at the current Length, like HFID. Since Add isn't called, the estimate number of generated HFIDs reported
+// by the store stays zero. The Permutation must not change once HFIDs have been generated, and the same generator must
+// not be used with HFID too.
+//
+// The numbers are split into bands so that no number is encoded twice, even at different lengths (e.g. `A-5` and `A-05`
+// both encode 5): the first band contains the numbers of MinLength characters and each following band contains the
+// numbers of exactly one more character. The counter values at which the Length would be increased from one band to the
+// next are permuted within the band.
+type CounterSource struct {
+	Store       CounterStore
+	Permutation Permutation
+}
+
+// Generate increments the counter of the generator and encodes its permuted value
+func (cs CounterSource) Generate(ctx context.Context, g Generator) (string, error) {
+	if g.Partitions > 1 {
+		return "", fmt.Errorf("generator '%s' is partitioned which is not supported by CounterSource", g.Name)
+	}
+	storedG, _, err := cs.Store.InsertOrGet(ctx, g)
+	if err != nil {
+		return "", err
+	}
+	// The template is a property of the caller rather than the store. The HFIDs of a template always have all its
+	// characters, which is safe since the bands never encode the same number twice.
+	storedG.Template = g.Template
+	c, err := cs.Store.Increment(ctx, g.Name)
+	if err != nil {
+		return "", err
+	}
+
+	// Increase the Length until the counter is below 50% of the HFIDs at the Length
+	for {
+		maxC, err := storedG.countHFIDs()
+		if err != nil {
+			return "", err
+		}
+		if c+1 <= int64(float64(maxC)*growthRatio) {
+			n, err := cs.number(storedG, c)
+			if err != nil {
+				return "", err
+			}
+			return storedG.encodeHFID(n)
+		}
+		grownG := storedG
+		grownG.Length++
+		err = cs.Store.Upsert(ctx, grownG)
+		var conflictErr ConflictError
+		if errors.As(err, &conflictErr) {
+			if storedG, _, err = cs.Store.InsertOrGet(ctx, g); err != nil {
+				return "", err
+			}
+			storedG.Template = g.Template
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		storedG = grownG
+	}
+}
+
+// number returns the number encoded by the HFID of the counter c. It finds the band of c, starting from the numbers of
+// MinLength characters, and permutes the position of c within the counter values of the band over the numbers of the
+// band.
+func (cs CounterSource) number(g Generator, c int64) (int64, error) {
+	band := g
+	if band.Length = g.MinLength; band.Length == 0 {
+		band.Length = 1
+	}
+	// The lowest number and counter value of the band
+	lowN, lowC := int64(0), int64(0)
+	for {
+		maxC, err := band.countHFIDs()
+		if err != nil {
+			return 0, err
+		}
+		highC := int64(float64(maxC) * growthRatio)
+		if c < highC {
+			n, err := cs.Permutation.Permute(c-lowC, maxC-lowN)
+			return lowN + n, err
+		}
+		lowN, lowC = maxC, highC
+		band.Length++
+	}
+}
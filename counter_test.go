@@ -0,0 +1,136 @@
+package hfid
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterSource_Generate(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Prefix: "A-", Encoding: NumericEncoding, MinLength: 1, Length: 1}
+	p := Permutation{Key: []byte("secret")}
+
+	t.Run("Generates unique HFIDs without retries", func(t *testing.T) {
+		mcs := NewMockCounterStore(t)
+		mcs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil)
+		for c := int64(0); c < 5; c++ {
+			mcs.On("Increment", ctx, g.Name).Return(c, nil).Once()
+		}
+		cs := CounterSource{Store: mcs, Permutation: p}
+
+		seen := map[string]bool{}
+		for i := 0; i < 5; i++ {
+			id, err := cs.Generate(ctx, g)
+			assert.NoError(t, err)
+			assert.Regexp(t, "^A-[0-9]$", id)
+			assert.False(t, seen[id])
+			seen[id] = true
+		}
+		mcs.AssertExpectations(t)
+	})
+
+	t.Run("Increases the Length when the counter reaches 50% of the HFIDs", func(t *testing.T) {
+		grownG := g
+		grownG.Length++
+		mcs := NewMockCounterStore(t)
+		mcs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil).Once()
+		mcs.On("Increment", ctx, g.Name).Return(int64(5), nil).Once()
+		mcs.On("Upsert", ctx, grownG).Return(ConflictError{Name: g.Name, Reason: "mock conflict"}).Once()
+		mcs.On("InsertOrGet", ctx, g).Return(grownG, int64(0), nil).Once()
+
+		id, err := CounterSource{Store: mcs, Permutation: p}.Generate(ctx, g)
+		assert.NoError(t, err)
+		n, err := Parse(grownG, id)
+		assert.NoError(t, err)
+		// 5 is the first counter value of the band of the numbers of 2 characters
+		c, err := p.Invert(n-10, 90)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), c)
+		assert.Regexp(t, "^A-[1-9][0-9]$", id)
+		mcs.AssertExpectations(t)
+	})
+
+	t.Run("Never encodes the same number twice across increases of the Length", func(t *testing.T) {
+		cs := CounterSource{Store: &memoryCounterStore{}, Permutation: p}
+		seenIDs := map[string]bool{}
+		seenNumbers := map[int64]bool{}
+		var ids []string
+		// The Length is increased to 2, 3 and 4
+		for i := 0; i < 600; i++ {
+			id, err := cs.Generate(ctx, g)
+			assert.NoError(t, err)
+			assert.False(t, seenIDs[id], "duplicate HFID %s", id)
+			seenIDs[id] = true
+			ids = append(ids, id)
+		}
+
+		storedG, _, err := cs.Store.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(4), storedG.Length)
+		for _, id := range ids {
+			n, err := Parse(storedG, id)
+			assert.NoError(t, err)
+			assert.False(t, seenNumbers[n], "HFID %s encodes %d which has been encoded before", id, n)
+			seenNumbers[n] = true
+		}
+	})
+
+	t.Run("Never encodes the same number twice across increases of the Length of a template", func(t *testing.T) {
+		tg, err := g.WithTemplate("INV-9999")
+		assert.NoError(t, err)
+		cs := CounterSource{Store: &memoryCounterStore{}, Permutation: p}
+		seenIDs := map[string]bool{}
+		// The Length is increased to 2, 3 and 4 while the HFIDs always have 4 digits
+		for i := 0; i < 600; i++ {
+			id, err := cs.Generate(ctx, *tg)
+			assert.NoError(t, err)
+			assert.Regexp(t, "^A-INV-[0-9]{4}$", id)
+			assert.False(t, seenIDs[id], "duplicate HFID %s", id)
+			seenIDs[id] = true
+		}
+	})
+
+	t.Run("Fails with partitioned generators", func(t *testing.T) {
+		pg, err := g.WithPartition(0, 2)
+		assert.NoError(t, err)
+		_, err = CounterSource{Store: NewMockCounterStore(t), Permutation: p}.Generate(ctx, *pg)
+		assert.Error(t, err)
+	})
+}
+
+// memoryCounterStore A CounterStore that keeps a single generator and its counter in memory
+type memoryCounterStore struct {
+	mu      sync.Mutex
+	g       *Generator
+	counter int64
+}
+
+func (s *memoryCounterStore) InsertOrGet(_ context.Context, g Generator) (Generator, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.g == nil {
+		s.g = &g
+	}
+	return *s.g, 0, nil
+}
+
+func (s *memoryCounterStore) Upsert(_ context.Context, g Generator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.g = &g
+	return nil
+}
+
+func (s *memoryCounterStore) Add(context.Context, int64, string) (bool, error) {
+	return true, nil
+}
+
+func (s *memoryCounterStore) Increment(context.Context, string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counter++
+	return s.counter - 1, nil
+}
@@ -0,0 +1,215 @@
+// Package dynamodb provides a DynamoDB implementation for GeneratorStore
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ddb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gitlab.com/alielgamal/hfid"
+	"strconv"
+)
+
+const nameKey = "name"
+const prefixKey = "p"
+const encodingKey = "e"
+const minLengthKey = "m"
+const lengthKey = "l"
+const countKey = "c"
+const generatorKey = "g"
+const nKey = "n"
+
+// GeneratorStore A Struct that wraps a DynamoDB Client and implements the GeneratorStore interface provided by HFID.
+// Generators are stored as items in the GeneratorsTable that are updated using writes conditioned on their Length, while
+// claimed HFIDs are stored as individual items in the IDsTable that are written using conditional PutItem commands.
+// Call CreateTables once to create both tables.
+type GeneratorStore struct {
+	Client          *ddb.Client
+	GeneratorsTable string
+	IDsTable        string
+}
+
+// CreateTables creates the GeneratorsTable with a string hash key "name" and the IDsTable with a string hash key "g"
+// and a numeric range key "n". Tables that already exist are left untouched.
+func (gs GeneratorStore) CreateTables(ctx context.Context) error {
+	_, err := gs.Client.CreateTable(ctx, &ddb.CreateTableInput{
+		TableName:            aws.String(gs.GeneratorsTable),
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String(nameKey), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String(nameKey), KeyType: types.KeyTypeHash}},
+		BillingMode:          types.BillingModePayPerRequest,
+	})
+	var inUse *types.ResourceInUseException
+	if err != nil && !errors.As(err, &inUse) {
+		return err
+	}
+
+	_, err = gs.Client.CreateTable(ctx, &ddb.CreateTableInput{
+		TableName: aws.String(gs.IDsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(generatorKey), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(nKey), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(generatorKey), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String(nKey), KeyType: types.KeyTypeRange},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err != nil && !errors.As(err, &inUse) {
+		return err
+	}
+	return nil
+}
+
+// InsertOrGet Implemented using a single UpdateItem command that sets the generator attributes only if they don't
+// exist and returns the stored generator along with the count of the HFIDs added to it.
+func (gs GeneratorStore) InsertOrGet(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	out, err := gs.Client.UpdateItem(ctx, &ddb.UpdateItemInput{
+		TableName: aws.String(gs.GeneratorsTable),
+		Key:       nameAttributes(g.Name),
+		UpdateExpression: aws.String("SET #p = if_not_exists(#p, :p), #e = if_not_exists(#e, :e), " +
+			"#m = if_not_exists(#m, :m), #l = if_not_exists(#l, :l), " +
+			"#c = if_not_exists(#c, :zero)"),
+		ExpressionAttributeNames: map[string]string{
+			"#p": prefixKey, "#e": encodingKey, "#m": minLengthKey, "#l": lengthKey, "#c": countKey,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":p":    &types.AttributeValueMemberS{Value: g.Prefix},
+			":e":    &types.AttributeValueMemberS{Value: string(g.Encoding)},
+			":m":    numberAttribute(int64(g.MinLength)),
+			":l":    numberAttribute(int64(g.Length)),
+			":zero": numberAttribute(0),
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		return g, 0, err
+	}
+
+	item := out.Attributes
+	p, err := toString(item[prefixKey])
+	if err != nil {
+		return g, 0, fmt.Errorf("invalid Prefix stored for Generator name '%s': %s", g.Name, err)
+	}
+	g.Prefix = p
+
+	e, err := toString(item[encodingKey])
+	if err != nil {
+		return g, 0, fmt.Errorf("invalid Encoding stored for Generator name '%s': %s", g.Name, err)
+	}
+	g.Encoding = hfid.Encoding(e)
+
+	ml, err := toUint8(item[minLengthKey])
+	if err != nil {
+		return g, 0, fmt.Errorf("invalid MinLength stored for Generator name '%s': %s", g.Name, err)
+	}
+	g.MinLength = ml
+
+	l, err := toUint8(item[lengthKey])
+	if err != nil {
+		return g, 0, fmt.Errorf("invalid Length stored for Generator name '%s': %s", g.Name, err)
+	}
+	g.Length = l
+
+	c, err := toInt64(item[countKey])
+	if err != nil {
+		return g, 0, fmt.Errorf("invalid count stored for Generator name '%s': %s", g.Name, err)
+	}
+	return g, c, nil
+}
+
+// Upsert Implemented using an UpdateItem command that is conditioned on the stored Length not being greater than the
+// Length of g, which is the guard against concurrent updates: HFID only upserts a generator to increase its Length, so a
+// hfid.ConflictError is returned if another node increased it beyond the Length of g, i.e. g is stale and would shrink
+// the generator. The count of the HFIDs added to the generator is left untouched.
+func (gs GeneratorStore) Upsert(ctx context.Context, g hfid.Generator) error {
+	_, err := gs.Client.UpdateItem(ctx, &ddb.UpdateItemInput{
+		TableName:           aws.String(gs.GeneratorsTable),
+		Key:                 nameAttributes(g.Name),
+		UpdateExpression:    aws.String("SET #p = :p, #e = :e, #m = :m, #l = :l, #c = if_not_exists(#c, :zero)"),
+		ConditionExpression: aws.String("attribute_not_exists(#l) OR #l <= :l"),
+		ExpressionAttributeNames: map[string]string{
+			"#p": prefixKey, "#e": encodingKey, "#m": minLengthKey, "#l": lengthKey, "#c": countKey,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":p":    &types.AttributeValueMemberS{Value: g.Prefix},
+			":e":    &types.AttributeValueMemberS{Value: string(g.Encoding)},
+			":m":    numberAttribute(int64(g.MinLength)),
+			":l":    numberAttribute(int64(g.Length)),
+			":zero": numberAttribute(0),
+		},
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return hfid.ConflictError{Name: g.Name, Reason: "the stored Length is greater than " + strconv.Itoa(int(g.Length))}
+	}
+	return err
+}
+
+// Add Implemented using a PutItem command into the IDsTable conditioned on the item not existing. A failed condition
+// means the HFID has been added before. Otherwise, the count of the generator is incremented.
+func (gs GeneratorStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	_, err := gs.Client.PutItem(ctx, &ddb.PutItemInput{
+		TableName: aws.String(gs.IDsTable),
+		Item: map[string]types.AttributeValue{
+			generatorKey: &types.AttributeValueMemberS{Value: gName},
+			nKey:         numberAttribute(hfid),
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#g)"),
+		ExpressionAttributeNames: map[string]string{"#g": generatorKey},
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = gs.Client.UpdateItem(ctx, &ddb.UpdateItemInput{
+		TableName:                 aws.String(gs.GeneratorsTable),
+		Key:                       nameAttributes(gName),
+		UpdateExpression:          aws.String("ADD #c :one"),
+		ExpressionAttributeNames:  map[string]string{"#c": countKey},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":one": numberAttribute(1)},
+	})
+	return true, err
+}
+
+func nameAttributes(gName string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{nameKey: &types.AttributeValueMemberS{Value: gName}}
+}
+
+func numberAttribute(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func toString(av types.AttributeValue) (string, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value, nil
+	default:
+		return "", fmt.Errorf("expected string attribute, but found %T", av)
+	}
+}
+
+func toInt64(av types.AttributeValue) (int64, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberN:
+		return strconv.ParseInt(v.Value, 10, 64)
+	default:
+		return 0, fmt.Errorf("expected number attribute, but found %T", av)
+	}
+}
+
+func toUint8(av types.AttributeValue) (uint8, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberN:
+		n, err := strconv.ParseUint(v.Value, 10, 8)
+		return uint8(n), err
+	default:
+		return 0, fmt.Errorf("expected number attribute, but found %T", av)
+	}
+}
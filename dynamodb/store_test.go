@@ -0,0 +1,251 @@
+package dynamodb
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	ddb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"os"
+	"strings"
+	"testing"
+)
+
+var dynamoClient *ddb.Client
+
+func TestMain(m *testing.M) {
+	endpoint, set := os.LookupEnv("DYNAMODB_ENDPOINT")
+	if !set {
+		endpoint = "http://localhost:8000"
+	}
+	dynamoClient = ddb.New(ddb.Options{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("local", "local", ""),
+		EndpointResolver: ddb.EndpointResolverFromURL(endpoint),
+	})
+
+	os.Exit(m.Run())
+}
+
+func prepareStore(t *testing.T) GeneratorStore {
+	tableName := strings.ReplaceAll(t.Name(), "/", "_")
+	gs := GeneratorStore{
+		Client:          dynamoClient,
+		GeneratorsTable: tableName,
+		IDsTable:        tableName + "_ids",
+	}
+	_, _ = dynamoClient.DeleteTable(context.Background(), &ddb.DeleteTableInput{TableName: aws.String(gs.GeneratorsTable)})
+	_, _ = dynamoClient.DeleteTable(context.Background(), &ddb.DeleteTableInput{TableName: aws.String(gs.IDsTable)})
+	assert.NoError(t, gs.CreateTables(context.Background()))
+
+	return gs
+}
+
+func TestGeneratorStore_InsertOrGet(t *testing.T) {
+	name := "test"
+	prefix := "t-"
+	encoding := hfid.Encoding(hfid.DefaultEncoding)
+	minLength := uint8(1)
+	length := uint8(2)
+
+	t.Run("new generator is added zero count", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		foundG, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(0), c)
+	})
+
+	t.Run("existing generator is returned", func(t *testing.T) {
+		existingG, err := hfid.NewGenerator(name, "t2-", hfid.NumericEncoding, 2, 3)
+		assert.NoError(t, err)
+
+		gs := prepareStore(t)
+		_, _, err = gs.InsertOrGet(context.Background(), *existingG)
+		assert.NoError(t, err)
+		_, err = gs.Add(context.Background(), 12345, name)
+		assert.NoError(t, err)
+
+		// Attempt to add another generator with the same name
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+
+		// The returned generator should be the existing one not the new one
+		foundG, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *existingG, foundG)
+		assert.Equal(t, int64(1), c)
+	})
+
+	t.Run("fails if the existing generator data is corrupt", func(t *testing.T) {
+		tcs := []struct {
+			name      string
+			minLength string
+			length    string
+		}{
+			{"minLength negative", "-1", "0"},
+			{"minLength overflow", "1000", "0"},
+			{"length negative", "0", "-1"},
+			{"length overflow", "0", "1000"},
+		}
+
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				gs := prepareStore(t)
+				_, err := dynamoClient.PutItem(context.Background(), &ddb.PutItemInput{
+					TableName: aws.String(gs.GeneratorsTable),
+					Item: map[string]types.AttributeValue{
+						nameKey:      &types.AttributeValueMemberS{Value: name},
+						prefixKey:    &types.AttributeValueMemberS{Value: prefix},
+						encodingKey:  &types.AttributeValueMemberS{Value: string(encoding)},
+						minLengthKey: &types.AttributeValueMemberN{Value: tc.minLength},
+						lengthKey:    &types.AttributeValueMemberN{Value: tc.length},
+					},
+				})
+				assert.NoError(t, err)
+
+				_, _, err = gs.InsertOrGet(context.Background(), hfid.Generator{Name: name})
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("returns an error when dynamodb fails", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.GeneratorsTable = "non-existent"
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		_, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), c)
+	})
+}
+
+func TestGeneratorStore_Upsert(t *testing.T) {
+	name := "test"
+	prefix := "t-"
+	encoding := hfid.Encoding(hfid.DefaultEncoding)
+	minLength := uint8(1)
+	length := uint8(2)
+
+	t.Run("creates a new generator if it doesn't exist", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		err = gs.Upsert(context.Background(), *g)
+		assert.NoError(t, err)
+
+		newG, err := hfid.NewGenerator(name, "t2-", hfid.NumericEncoding, 2, 3)
+		assert.NoError(t, err)
+
+		foundG, c, err := gs.InsertOrGet(context.Background(), *newG)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(0), c)
+	})
+
+	t.Run("updates existing generator", func(t *testing.T) {
+		existingG, err := hfid.NewGenerator(name, "t2-", hfid.NumericEncoding, 2, 2)
+		assert.NoError(t, err)
+
+		gs := prepareStore(t)
+		_, _, err = gs.InsertOrGet(context.Background(), *existingG)
+		assert.NoError(t, err)
+		_, err = gs.Add(context.Background(), 12345, name)
+		assert.NoError(t, err)
+
+		// Upsert twice
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		assert.NoError(t, gs.Upsert(context.Background(), *g))
+		assert.NoError(t, gs.Upsert(context.Background(), *g))
+
+		// The returned generator should be the updated one with the old count
+		foundG, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(1), c)
+	})
+
+	t.Run("returns a ConflictError when the stored Length is greater", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+
+		// A stale node read the generator before another node increased its Length
+		staleG, _, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		grownG := staleG
+		grownG.Length++
+		assert.NoError(t, gs.Upsert(context.Background(), grownG))
+
+		err = gs.Upsert(context.Background(), staleG)
+		var conflictErr hfid.ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
+
+		// The generator hasn't shrunk
+		foundG, _, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, grownG, foundG)
+	})
+
+	t.Run("returns an error when dynamodb fails", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.GeneratorsTable = "non-existent"
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		assert.Error(t, gs.Upsert(context.Background(), *g))
+	})
+}
+
+func TestGeneratorStore_Add(t *testing.T) {
+	name := "test"
+	g, err := hfid.NewGenerator(name, "t-", hfid.DefaultEncoding, 1, 2)
+	assert.NoError(t, err)
+
+	addFirstHFID := func(t *testing.T, gs GeneratorStore, id int64) {
+		err := gs.Upsert(context.Background(), *g)
+		assert.NoError(t, err)
+
+		isNew, err := gs.Add(context.Background(), id, name)
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+	}
+
+	t.Run("returns true when the element is unique", func(t *testing.T) {
+		gs := prepareStore(t)
+
+		addFirstHFID(t, gs, 0)
+		isNew, err := gs.Add(context.Background(), 1, name)
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+
+		_, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), c)
+	})
+
+	t.Run("returns false when the element is duplicated", func(t *testing.T) {
+		gs := prepareStore(t)
+
+		addFirstHFID(t, gs, 0)
+		isNew, err := gs.Add(context.Background(), 0, name)
+		assert.NoError(t, err)
+		assert.False(t, isNew)
+
+		_, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), c)
+	})
+
+	t.Run("returns an error when dynamodb fails", func(t *testing.T) {
+		gs := prepareStore(t)
+		gs.IDsTable = "non-existent"
+		_, err := gs.Add(context.Background(), 0, name)
+		assert.Error(t, err)
+	})
+}
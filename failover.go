@@ -0,0 +1,152 @@
+package hfid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FailoverStore A GeneratorStore that fronts a Primary and a Secondary store so that HFIDs can still be generated when
+// one of them is down. The HFIDs are partitioned between the two stores using a hash of the HFID (which keeps the
+// halves balanced for partitioned generators too), hence each store guarantees the uniqueness of its own partition
+// without replicating the other. When a store is down, Add reports the HFIDs of its partition as generated before so
+// that HFID draws another one that belongs to the available store. Generators are written to both stores and read from
+// both, preferring the longest one. FailoverStore doesn't implement Claimer. A FailoverStore must not be copied after
+// first use.
+type FailoverStore struct {
+	Primary   GeneratorStore
+	Secondary GeneratorStore
+
+	// RecheckInterval is how long a store that failed is considered down before it is called again. 5 seconds is used
+	// when zero. A store that is down is still called when the other store is down too.
+	RecheckInterval time.Duration
+
+	mu       sync.Mutex
+	failedAt [2]time.Time
+}
+
+// InsertOrGet calls InsertOrGet of both stores and returns the Generator with the longest Length. The estimate number
+// of generated HFIDs is the sum of the estimates of both stores, or twice the estimate of the available store if the
+// other one failed. An error is returned only if both stores fail.
+func (fs *FailoverStore) InsertOrGet(ctx context.Context, g Generator) (Generator, int64, error) {
+	var gs [2]Generator
+	var counts [2]int64
+	errs := fs.both(func(i int, s GeneratorStore) error {
+		var err error
+		gs[i], counts[i], err = s.InsertOrGet(ctx, g)
+		return err
+	})
+	switch {
+	case errs[0] == nil && errs[1] == nil:
+		if gs[1].Length > gs[0].Length {
+			return gs[1], counts[0] + counts[1], nil
+		}
+		return gs[0], counts[0] + counts[1], nil
+	case errs[0] == nil:
+		return gs[0], 2 * counts[0], nil
+	case errs[1] == nil:
+		return gs[1], 2 * counts[1], nil
+	default:
+		return g, 0, bothFailed(errs)
+	}
+}
+
+// Upsert calls Upsert of both stores. A ConflictError is returned if any of the stores returns one. Otherwise, an
+// error is returned only if both stores fail.
+func (fs *FailoverStore) Upsert(ctx context.Context, g Generator) error {
+	errs := fs.both(func(_ int, s GeneratorStore) error {
+		return s.Upsert(ctx, g)
+	})
+	for _, err := range errs {
+		var conflictErr ConflictError
+		if errors.As(err, &conflictErr) {
+			return err
+		}
+	}
+	if errs[0] != nil && errs[1] != nil {
+		return bothFailed(errs)
+	}
+	return nil
+}
+
+// Add calls Add of the store that owns the partition of hfid. If that store is down while the other one isn't, false
+// is returned without an error so that HFID draws another HFID.
+func (fs *FailoverStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	i := storeIndex(hfid)
+	if !fs.available(i) && fs.available(1-i) {
+		return false, nil
+	}
+	isNew, err := fs.store(i).Add(ctx, hfid, gName)
+	fs.report(i, err)
+	if err != nil && fs.available(1-i) {
+		return false, nil
+	}
+	return isNew, err
+}
+
+// both calls f with each store concurrently and returns their errors
+func (fs *FailoverStore) both(f func(i int, s GeneratorStore) error) [2]error {
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f(i, fs.store(i))
+			fs.report(i, errs[i])
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (fs *FailoverStore) store(i int) GeneratorStore {
+	if i == 0 {
+		return fs.Primary
+	}
+	return fs.Secondary
+}
+
+// available returns false if the store i failed within the RecheckInterval
+func (fs *FailoverStore) available(i int) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.failedAt[i].IsZero() || time.Since(fs.failedAt[i]) >= fs.recheckInterval()
+}
+
+// report marks the store i as down if err isn't nil, or as available otherwise. ConflictErrors are not failures.
+func (fs *FailoverStore) report(i int, err error) {
+	var conflictErr ConflictError
+	if errors.As(err, &conflictErr) {
+		return
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err != nil {
+		fs.failedAt[i] = time.Now()
+	} else {
+		fs.failedAt[i] = time.Time{}
+	}
+}
+
+func (fs *FailoverStore) recheckInterval() time.Duration {
+	if fs.RecheckInterval > 0 {
+		return fs.RecheckInterval
+	}
+	return 5 * time.Second
+}
+
+// storeIndex returns the index of the store that owns the partition of hfid using the finalizer of SplitMix64
+func storeIndex(hfid int64) int {
+	h := uint64(hfid)
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	h ^= h >> 31
+	return int(h & 1)
+}
+
+func bothFailed(errs [2]error) error {
+	return fmt.Errorf("both stores failed, primary: %w, secondary: %s", errs[0], errs[1])
+}
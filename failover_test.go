@@ -0,0 +1,117 @@
+package hfid
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFailoverStore_InsertOrGet(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+	longG := g
+	longG.Length = 3
+	storeErr := fmt.Errorf("mock error")
+
+	tests := []struct {
+		name      string
+		primary   []interface{}
+		secondary []interface{}
+		wantG     Generator
+		wantCount int64
+		wantErr   bool
+	}{
+		{"Returns the longest Generator and the sum of the counts", []interface{}{g, int64(3), nil}, []interface{}{longG, int64(4), nil}, longG, 7, false},
+		{"Fails over to the Secondary", []interface{}{g, int64(0), storeErr}, []interface{}{g, int64(4), nil}, g, 8, false},
+		{"Fails over to the Primary", []interface{}{g, int64(3), nil}, []interface{}{g, int64(0), storeErr}, g, 6, false},
+		{"Fails if both stores fail", []interface{}{g, int64(0), storeErr}, []interface{}{g, int64(0), storeErr}, g, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := NewMockGeneratorStore(t)
+			primary.On("InsertOrGet", ctx, g).Return(tt.primary...)
+			secondary := NewMockGeneratorStore(t)
+			secondary.On("InsertOrGet", ctx, g).Return(tt.secondary...)
+
+			gotG, gotCount, err := (&FailoverStore{Primary: primary, Secondary: secondary}).InsertOrGet(ctx, g)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, storeErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantG, gotG)
+			assert.Equal(t, tt.wantCount, gotCount)
+		})
+	}
+}
+
+func TestFailoverStore_Upsert(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+	storeErr := fmt.Errorf("mock error")
+
+	t.Run("Succeeds if one store succeeds", func(t *testing.T) {
+		primary := NewMockGeneratorStore(t)
+		primary.On("Upsert", ctx, g).Return(storeErr)
+		secondary := NewMockGeneratorStore(t)
+		secondary.On("Upsert", ctx, g).Return(nil)
+		assert.NoError(t, (&FailoverStore{Primary: primary, Secondary: secondary}).Upsert(ctx, g))
+	})
+
+	t.Run("Returns a ConflictError of any store", func(t *testing.T) {
+		primary := NewMockGeneratorStore(t)
+		primary.On("Upsert", ctx, g).Return(nil)
+		secondary := NewMockGeneratorStore(t)
+		secondary.On("Upsert", ctx, g).Return(ConflictError{Name: g.Name, Reason: "mock conflict"})
+		err := (&FailoverStore{Primary: primary, Secondary: secondary}).Upsert(ctx, g)
+		assert.ErrorAs(t, err, &ConflictError{})
+	})
+}
+
+func TestFailoverStore_Add(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+	storeErr := fmt.Errorf("mock error")
+
+	t.Run("Generates HFIDs of the Secondary partition only while the Primary is down", func(t *testing.T) {
+		primary := NewMockGeneratorStore(t)
+		primary.On("InsertOrGet", ctx, g).Return(g, int64(0), storeErr)
+		secondary := NewMockGeneratorStore(t)
+		secondary.On("InsertOrGet", ctx, g).Return(g, int64(0), nil)
+		secondary.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
+
+		fs := &FailoverStore{Primary: primary, Secondary: secondary}
+		for i := 0; i < 10; i++ {
+			id, err := HFID(ctx, g, fs, *rand.New(rand.NewSource(int64(i))))
+			assert.NoError(t, err)
+			n, err := Parse(g, id)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, storeIndex(n))
+		}
+		primary.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fails if both stores are down", func(t *testing.T) {
+		var primaryHFID, secondaryHFID int64
+		for storeIndex(secondaryHFID) != 1 {
+			secondaryHFID++
+		}
+		primary := NewMockGeneratorStore(t)
+		primary.On("Add", ctx, primaryHFID, g.Name).Return(false, storeErr)
+		secondary := NewMockGeneratorStore(t)
+		secondary.On("Add", ctx, secondaryHFID, g.Name).Return(false, storeErr)
+
+		fs := &FailoverStore{Primary: primary, Secondary: secondary}
+		isNew, err := fs.Add(ctx, primaryHFID, g.Name)
+		assert.NoError(t, err, "the Secondary is still considered available")
+		assert.False(t, isNew)
+		_, err = fs.Add(ctx, secondaryHFID, g.Name)
+		assert.ErrorIs(t, err, storeErr)
+		_, err = fs.Add(ctx, primaryHFID, g.Name)
+		assert.ErrorIs(t, err, storeErr, "a store that is down is called when the other store is down too")
+	})
+}
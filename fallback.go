@@ -0,0 +1,248 @@
+package hfid
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fallbackBits The minimum number of random bits in a fallback HFID, which is the same as a random UUID
+const fallbackBits = 122
+
+// Fallback A Source that wraps another Source with a circuit breaker. When the wrapped Source fails (e.g. the store is
+// down or slower than Timeout), a fallback HFID is returned instead of an error. After FailureThreshold consecutive
+// failures the circuit opens and fallback HFIDs are returned without calling the wrapped Source until OpenDuration
+// passes, then a single call is let through to check whether the wrapped Source has recovered. Fallback HFIDs are
+// generated by FallbackHFID and recorded using the Recorder so that they can be reconciled once the store recovers.
+// A Fallback must not be copied after first use.
+type Fallback struct {
+	Source Source
+
+	// FailureThreshold is the number of consecutive failures that opens the circuit. 5 is used when zero.
+	FailureThreshold int
+
+	// Timeout is the maximum duration of a call to the wrapped Source. Calls taking longer are counted as failures.
+	// Calls are not limited when zero.
+	Timeout time.Duration
+
+	// OpenDuration is how long the circuit stays open before a call is let through. 30 seconds is used when zero.
+	OpenDuration time.Duration
+
+	// Recorder records the fallback HFIDs. Fallback HFIDs are not recorded when nil.
+	Recorder FallbackRecorder
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// FallbackRecorder An interface to record the fallback HFIDs returned by Fallback so that they can be reconciled once
+// the store recovers (e.g. to replace them with HFIDs or to keep them from being reused).
+type FallbackRecorder interface {
+	Record(ctx context.Context, gName string, hfid string) error
+}
+
+// MemoryFallbackRecorder A FallbackRecorder that keeps the fallback HFIDs in memory until they are drained. It is safe
+// for concurrent use.
+type MemoryFallbackRecorder struct {
+	mu    sync.Mutex
+	hfids map[string][]string
+}
+
+// Record keeps hfid in memory under gName
+func (r *MemoryFallbackRecorder) Record(_ context.Context, gName string, hfid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hfids == nil {
+		r.hfids = map[string][]string{}
+	}
+	r.hfids[gName] = append(r.hfids[gName], hfid)
+	return nil
+}
+
+// Drain returns the recorded fallback HFIDs by generator name and forgets them
+func (r *MemoryFallbackRecorder) Drain() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hfids := r.hfids
+	r.hfids = nil
+	return hfids
+}
+
+// FallbackError is returned by Parse when hfid is a fallback HFID generated by FallbackHFID, hence it doesn't encode a
+// number.
+type FallbackError struct {
+	Name string
+	HFID string
+}
+
+func (e FallbackError) Error() string {
+	return fmt.Sprintf("HFID '%s' of type '%s' is a fallback HFID", e.HFID, e.Name)
+}
+
+// Generate calls the wrapped Source unless the circuit is open. A fallback HFID is returned if the circuit is open or
+// the call fails. An error is returned only if ctx is done, the generator is rate limited or the fallback HFID cannot be
+// generated or recorded. Errors wrapping ErrRateLimited are returned as is without counting as failures, hence the
+// limit cannot be bypassed using fallback HFIDs.
+func (f *Fallback) Generate(ctx context.Context, g Generator) (string, error) {
+	if f.allow() {
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if f.Timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, f.Timeout)
+		}
+		start := f.timeNow()
+		result, err := f.Source.Generate(callCtx, g)
+		cancel()
+		if ctx.Err() != nil {
+			// The caller gave up, hence the wrapped Source isn't to blame
+			f.release()
+			return "", ctx.Err()
+		}
+		if errors.Is(err, ErrRateLimited) {
+			// The limit has been reached, which tells nothing about the health of the wrapped Source
+			f.release()
+			return "", err
+		}
+		slow := f.Timeout > 0 && f.timeNow().Sub(start) > f.Timeout
+		f.report(err == nil && !slow)
+		if err == nil {
+			return result, nil
+		}
+	}
+
+	result, err := FallbackHFID(g)
+	if err != nil {
+		return "", err
+	}
+	if f.Recorder != nil {
+		if err := f.Recorder.Record(ctx, g.Name, result); err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
+
+// Open returns true if the circuit is open, i.e. fallback HFIDs are returned without calling the wrapped Source
+func (f *Fallback) Open() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failures >= f.failureThreshold()
+}
+
+// allow returns true if the wrapped Source can be called. Once OpenDuration passes, a single call is let through.
+func (f *Fallback) allow() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures < f.failureThreshold() {
+		return true
+	}
+	if f.probing || f.timeNow().Sub(f.openedAt) < f.openDuration() {
+		return false
+	}
+	f.probing = true
+	return true
+}
+
+// release lets another call through without reporting the outcome of the current one
+func (f *Fallback) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probing = false
+}
+
+// report closes the circuit on success and opens it once FailureThreshold consecutive failures are reported
+func (f *Fallback) report(success bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probing = false
+	if success {
+		f.failures = 0
+		return
+	}
+	f.failures++
+	if f.failures >= f.failureThreshold() {
+		f.openedAt = f.timeNow()
+	}
+}
+
+func (f *Fallback) failureThreshold() int {
+	if f.FailureThreshold > 0 {
+		return f.FailureThreshold
+	}
+	return 5
+}
+
+func (f *Fallback) openDuration() time.Duration {
+	if f.OpenDuration > 0 {
+		return f.OpenDuration
+	}
+	return 30 * time.Second
+}
+
+func (f *Fallback) timeNow() time.Time {
+	if f.now != nil {
+		return f.now()
+	}
+	return time.Now()
+}
+
+// FallbackHFID generates a random HFID that doesn't need a store. It has the Prefix of g followed by random characters
+// of its Encoding, and it is longer than any HFID that g can generate (including the HFIDs of its Template), hence Parse
+// returns a FallbackError for it. It has at least as many random bits as a random UUID.
+func FallbackHFID(g Generator) (string, error) {
+	if err := g.Encoding.Valid(); err != nil {
+		return "", err
+	}
+	n := fallbackLength(g)
+	base := big.NewInt(int64(len(g.Encoding)))
+	var sb strings.Builder
+	sb.WriteString(g.Prefix)
+	for i := 0; i < n; i++ {
+		c, err := rand.Int(rand.Reader, base)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(g.Encoding[c.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// isFallbackHFID returns true if hfid has the Prefix of g followed by as many characters of its Encoding as the HFIDs
+// generated by FallbackHFID
+func isFallbackHFID(g Generator, hfid string) bool {
+	if !strings.HasPrefix(hfid, g.Prefix) || len(g.Encoding) < 2 {
+		return false
+	}
+	hfid = hfid[len(g.Prefix):]
+	if len(hfid) != fallbackLength(g) {
+		return false
+	}
+	for i := 0; i < len(hfid); i++ {
+		if strings.IndexByte(string(g.Encoding), hfid[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fallbackLength returns the number of characters of the Encoding of g needed to have fallbackBits random bits. If the
+// HFIDs of the Template of g are as long, the fallback HFIDs are made one character longer so that they cannot be
+// confused with them.
+func fallbackLength(g Generator) int {
+	n := int(math.Ceil(fallbackBits / math.Log2(float64(len(g.Encoding)))))
+	if g.Template == "" {
+		return n
+	}
+	if parts, err := parseTemplate(g.Template, g.Encoding); err == nil && len(parts) >= n {
+		return len(parts) + 1
+	}
+	return n
+}
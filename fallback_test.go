@@ -0,0 +1,140 @@
+package hfid
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// sourceFunc A Source that calls the function
+type sourceFunc func(ctx context.Context, g Generator) (string, error)
+
+func (f sourceFunc) Generate(ctx context.Context, g Generator) (string, error) {
+	return f(ctx, g)
+}
+
+func TestFallback_Generate(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Prefix: "A-", Encoding: NumericEncoding, MinLength: 1, Length: 3}
+
+	t.Run("Returns the HFID of the wrapped Source", func(t *testing.T) {
+		f := &Fallback{Source: sourceFunc(func(context.Context, Generator) (string, error) { return "A-042", nil })}
+		got, err := f.Generate(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, "A-042", got)
+	})
+
+	t.Run("Opens after consecutive failures and lets a call through after OpenDuration", func(t *testing.T) {
+		calls := 0
+		var sourceErr error = fmt.Errorf("mock error")
+		now := time.Now()
+		r := &MemoryFallbackRecorder{}
+		f := &Fallback{
+			Source: sourceFunc(func(context.Context, Generator) (string, error) {
+				calls++
+				return "A-042", sourceErr
+			}),
+			FailureThreshold: 2,
+			OpenDuration:     time.Minute,
+			Recorder:         r,
+			now:              func() time.Time { return now },
+		}
+
+		var fallbacks []string
+		for i := 0; i < 3; i++ {
+			got, err := f.Generate(ctx, g)
+			assert.NoError(t, err)
+			_, err = Parse(g, got)
+			assert.ErrorAs(t, err, &FallbackError{})
+			fallbacks = append(fallbacks, got)
+		}
+		assert.Equal(t, 2, calls, "the wrapped Source must not be called while the circuit is open")
+		assert.True(t, f.Open())
+		assert.Equal(t, map[string][]string{"a": fallbacks}, r.Drain())
+		assert.Nil(t, r.Drain())
+
+		now = now.Add(time.Minute)
+		sourceErr = nil
+		got, err := f.Generate(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, "A-042", got)
+		assert.Equal(t, 3, calls)
+		assert.False(t, f.Open())
+	})
+
+	t.Run("Counts calls slower than Timeout as failures", func(t *testing.T) {
+		f := &Fallback{
+			Source: sourceFunc(func(ctx context.Context, _ Generator) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			}),
+			FailureThreshold: 1,
+			Timeout:          time.Millisecond,
+		}
+		got, err := f.Generate(ctx, g)
+		assert.NoError(t, err)
+		assert.True(t, isFallbackHFID(g, got))
+		assert.True(t, f.Open())
+	})
+
+	t.Run("Returns the error when the caller gives up", func(t *testing.T) {
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		f := &Fallback{
+			Source:           sourceFunc(func(ctx context.Context, _ Generator) (string, error) { return "", ctx.Err() }),
+			FailureThreshold: 1,
+		}
+		_, err := f.Generate(cancelledCtx, g)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, f.Open())
+	})
+
+	t.Run("Returns the error when the generator is rate limited", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+		r := &MemoryFallbackRecorder{}
+		f := &Fallback{
+			Source: StoreSource{Store: mgs, Options: Options{
+				Limiter: &MemoryLimiter{},
+				Limit:   Limit{Rate: 0.001, Burst: 1},
+			}},
+			FailureThreshold: 1,
+			Recorder:         r,
+		}
+
+		_, err := f.Generate(ctx, g)
+		assert.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			_, err = f.Generate(ctx, g)
+			assert.ErrorIs(t, err, ErrRateLimited)
+		}
+		assert.False(t, f.Open())
+		assert.Empty(t, r.Drain())
+		mgs.AssertExpectations(t)
+	})
+}
+
+func TestFallbackHFID(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       Generator
+		pattern string
+	}{
+		{"Generates 37 digits for the NumericEncoding", Generator{Name: "a", Prefix: "A-", Encoding: NumericEncoding, Length: 18}, "^A-[0-9]{37}$"},
+		{"Generates 24 characters for the DefaultEncoding", Generator{Name: "a", Encoding: DefaultEncoding, Length: 12}, "^[0-9A-Z]{24}$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FallbackHFID(tt.g)
+			assert.NoError(t, err)
+			assert.Regexp(t, tt.pattern, got)
+			_, err = Parse(tt.g, got)
+			assert.Equal(t, FallbackError{Name: tt.g.Name, HFID: got}, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package hfid
+
+import "strings"
+
+// maxRejections The number of consecutive HFIDs rejected by the Filter after which HFID gives up
+const maxRejections = 1000
+
+// Filter An interface to reject drawn HFIDs that must not be generated, that can be set in Options
+type Filter interface {
+	// Reject returns true if the HFID must not be generated. hfid is passed without the Prefix of the generator.
+	Reject(hfid string) bool
+}
+
+// DefaultBlocklist A list of offensive English words that can be used with a BlocklistFilter
+var DefaultBlocklist = []string{
+	"ANAL", "ANUS", "ARSE", "ASS", "BITCH", "BOOB", "COCK", "CUM", "CUNT", "DICK", "DIKE", "DYKE", "FAG", "FCK", "FUCK",
+	"FUK", "HOMO", "JIZZ", "KKK", "NAZI", "NIGGA", "NIGGER", "PENIS", "PISS", "PORN", "PUSSY", "RAPE", "RETARD", "SEX",
+	"SHIT", "SLUT", "TIT", "TWAT", "VAGINA", "WANK", "WHORE",
+}
+
+// leetSpeak maps the characters that are commonly used in place of letters to the letters they may stand for
+var leetSpeak = map[byte]string{
+	'0': "O", '1': "IL", '2': "Z", '3': "E", '4': "A", '5': "S", '6': "G", '7': "T", '8': "B", '9': "G",
+	'@': "A", '$': "S", '!': "I", '|': "IL", '+': "T",
+}
+
+// BlocklistFilter A Filter that rejects HFIDs containing any of the Words, case-insensitively. Digits and symbols that
+// look like letters (leet speak, e.g. "5H17") are matched as the letters they stand for. Use NewBlocklistFilter to
+// create it since the Words must be in upper case.
+type BlocklistFilter struct {
+	Words []string
+}
+
+// NewBlocklistFilter creates a BlocklistFilter that rejects HFIDs containing any of words. Use DefaultBlocklist to
+// reject offensive English words, optionally appending custom words to it.
+func NewBlocklistFilter(words ...string) BlocklistFilter {
+	f := BlocklistFilter{Words: make([]string, 0, len(words))}
+	for _, w := range words {
+		if w != "" {
+			f.Words = append(f.Words, strings.ToUpper(w))
+		}
+	}
+	return f
+}
+
+// Reject returns true if hfid contains any of the Words
+func (f BlocklistFilter) Reject(hfid string) bool {
+	hfid = strings.ToUpper(hfid)
+	for _, w := range f.Words {
+		for i := 0; i+len(w) <= len(hfid); i++ {
+			if matchesAt(hfid, i, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAt returns true if word is found in hfid at i, where each character of hfid matches the same letter or the
+// letters it stands for in leet speak
+func matchesAt(hfid string, i int, word string) bool {
+	for j := 0; j < len(word); j++ {
+		c := hfid[i+j]
+		if c != word[j] && strings.IndexByte(leetSpeak[c], word[j]) < 0 {
+			return false
+		}
+	}
+	return true
+}
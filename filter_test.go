@@ -0,0 +1,72 @@
+package hfid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBlocklistFilter_Reject(t *testing.T) {
+	f := NewBlocklistFilter(append(DefaultBlocklist, "acme", "")...)
+	tests := []struct {
+		name string
+		hfid string
+		want bool
+	}{
+		{"Accepts HFIDs without blocked words", "7K2Q9X", false},
+		{"Rejects HFIDs containing a blocked word", "X7SHITQ", true},
+		{"Rejects blocked words case-insensitively", "xshitq", true},
+		{"Rejects blocked words written in leet speak", "5H17", true},
+		{"Rejects blocked words using 1 as an L", "S1UT", true},
+		{"Rejects custom words", "ACME42", true},
+		{"Rejects custom words in leet speak", "4CM3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, f.Reject(tt.hfid))
+		})
+	}
+}
+
+// filterFunc A Filter that calls the function
+type filterFunc func(hfid string) bool
+
+func (f filterFunc) Reject(hfid string) bool {
+	return f(hfid)
+}
+
+func TestOptions_Filter(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Prefix: "A-", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+
+	t.Run("Draws another HFID instead of a rejected one without adding it", func(t *testing.T) {
+		var rejected []string
+		f := filterFunc(func(hfid string) bool {
+			if len(rejected) < 3 {
+				rejected = append(rejected, hfid)
+				return true
+			}
+			return false
+		})
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil)
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+
+		id, err := Options{Filter: f}.HFID(ctx, g, mgs)
+		assert.NoError(t, err)
+		assert.Len(t, rejected, 3)
+		for _, r := range rejected {
+			assert.Regexp(t, "^[0-9]{2}$", r, "the prefix is not passed to the Filter")
+		}
+		assert.Regexp(t, "^A-[0-9]{2}$", id)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Fails if the Filter rejects every HFID", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		_, err := Options{Filter: filterFunc(func(string) bool { return true })}.HFID(ctx, g, mcs)
+		assert.EqualError(t, err, "1000 HFIDs drawn for generator 'a' have been rejected by the filter")
+	})
+}
@@ -3,6 +3,7 @@ package hfid
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"strings"
 )
 
@@ -14,6 +15,30 @@ type Generator struct {
 	Encoding  Encoding
 	MinLength uint8
 	Length    uint8
+
+	// Partitions is the number of disjoint partitions the HFIDs are split into, so that each region or node can generate
+	// HFIDs using its own store without coordinating with the others. HFIDs are not partitioned when 0 or 1. Stores don't
+	// persist Partitions and Partition, hence HFID uses the ones of the Generator passed to it.
+	Partitions uint8
+
+	// Partition is the index of the partition of this Generator in [0, Partitions). The partition contains the HFIDs
+	// whose remainder when divided by Partitions is Partition.
+	Partition uint8
+
+	// Template is the format of the HFIDs after the Prefix when they cannot be expressed using a single Encoding (e.g.
+	// `INV-2026-XXXX-XXXX` or `AAA-999`). Stores don't persist Template, hence HFID uses the one of the Generator passed
+	// to it. Please use WithTemplate to set it. The syntax is:
+	//  * `X` is a placeholder for a character of the Encoding
+	//  * `A` is a placeholder for an uppercase letter
+	//  * `9` is a placeholder for a digit
+	//  * `[...]` is a placeholder for a character of the alphabet between the brackets (e.g. `[ABC]`)
+	//  * `\` escapes the next character so that it is used literally (e.g. `\A`)
+	//  * any other character is used literally (e.g. separators like `-`)
+	// The number encoded by an HFID is a mixed radix number where each placeholder is a digit in the base of the size of
+	// its alphabet. Only the last Length placeholders are used, the ones before them are set to the first character of
+	// their alphabet, hence increasing the Length fills the placeholders from right to left. MinLength isn't used since
+	// HFIDs always have all the characters of the Template.
+	Template string
 }
 
 // GeneratorStore interface to store and update Generator Instances
@@ -24,7 +49,8 @@ type GeneratorStore interface {
 	InsertOrGet(ctx context.Context, g Generator) (Generator, int64, error)
 
 	// Upsert a Generator. If an existing Generator with the same name was found, update it without changing the
-	// hyperloglog attached to it. Otherwise, Insert a new Generator with a new hyperloglog.
+	// hyperloglog attached to it. Otherwise, Insert a new Generator with a new hyperloglog. Implementations may return a
+	// ConflictError if the Generator has been modified concurrently.
 	Upsert(ctx context.Context, g Generator) error
 
 	// Add hfid to the hyperloglog associated with the generator named gName. Return true if the hyperloglog was changed
@@ -32,6 +58,57 @@ type GeneratorStore interface {
 	Add(ctx context.Context, hfid int64, gName string) (bool, error)
 }
 
+// Claimer An optional interface that a GeneratorStore can implement to run the whole HFID generation cycle atomically
+// in a single round-trip. HFID uses it instead of InsertOrGet, Upsert and Add when the store implements it.
+type Claimer interface {
+	// Claim fetches the Generator named g.Name or creates it using g if it doesn't exist, then increases its Length if
+	// 50% of the HFIDs at the current length have been generated. Finally, hfid is added to the hyperloglog only if the
+	// resulting Length and the Encoding are the same as the ones of g (i.e. hfid has been drawn for the stored
+	// Generator).
+	Claim(ctx context.Context, g Generator, hfid int64) (ClaimResult, error)
+}
+
+// ClaimResult The outcome of Claimer.Claim
+type ClaimResult struct {
+	// Generator is the resulting Generator
+	Generator Generator
+
+	// Count is an estimate number of HFIDs that had been generated using the Generator before the claim
+	Count int64
+
+	// Grown is true if the claim increased the Length of the Generator
+	Grown bool
+
+	// Added is true if hfid has been added, false otherwise
+	Added bool
+}
+
+// GeneratorAdmin An optional interface that a GeneratorStore can implement to let operators inspect and manage the
+// generators it stores (e.g. using the hfid command-line tool).
+type GeneratorAdmin interface {
+	// Get fetches the Generator named gName without creating it. The function returns the Generator, an estimate number
+	// of HFIDs that have been generated using it and false if it wasn't found.
+	Get(ctx context.Context, gName string) (Generator, int64, bool, error)
+
+	// List returns the names of the generators in the store sorted alphabetically
+	List(ctx context.Context) ([]string, error)
+
+	// Delete the Generator named gName along with the hyperloglog attached to it. A Generator that is created again
+	// with the same name may generate HFIDs that have been generated before the deletion.
+	Delete(ctx context.Context, gName string) error
+}
+
+// ConflictError is returned by a GeneratorStore when a Generator cannot be updated because it has been modified
+// concurrently (e.g. another node increased its Length first). HFID recovers from it by re-reading the Generator.
+type ConflictError struct {
+	Name   string
+	Reason string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("generator '%s' has been modified concurrently: %s", e.Name, e.Reason)
+}
+
 // NewGenerator creates a new Generator after validating the arguments
 func NewGenerator(name string, prefix string, e Encoding, minLength uint8, length uint8) (*Generator, error) {
 	if strings.TrimSpace(name) == "" {
@@ -49,7 +126,7 @@ func NewGenerator(name string, prefix string, e Encoding, minLength uint8, lengt
 		return nil, fmt.Errorf("length '%d' cannot be less than MinLength '%d'", length, minLength)
 	}
 
-	result := Generator{name, prefix, e, minLength, length}
+	result := Generator{Name: name, Prefix: prefix, Encoding: e, MinLength: minLength, Length: length}
 
 	if _, err := result.maxHFID(); err != nil {
 		return nil, fmt.Errorf("encoding '%s' with Length %d would result overflow. This Generator cannot be used any more", e, length)
@@ -58,14 +135,56 @@ func NewGenerator(name string, prefix string, e Encoding, minLength uint8, lengt
 	return &result, nil
 }
 
+// WithPartition returns a copy of the Generator that generates the HFIDs of the partition only. Generators that share
+// the same Name, Prefix, Encoding and Partitions but have different partitions never generate the same HFID, even when
+// using different stores.
+func (it Generator) WithPartition(partition uint8, partitions uint8) (*Generator, error) {
+	it.Partition = partition
+	it.Partitions = partitions
+	if err := it.validPartition(); err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// WithTemplate returns a copy of the Generator that formats the HFIDs using template. See Generator.Template for the
+// syntax. An error is returned if template is invalid or has fewer placeholders than the Length.
+func (it Generator) WithTemplate(template string) (*Generator, error) {
+	it.Template = template
+	if _, err := it.maxHFID(); err != nil {
+		return nil, fmt.Errorf("invalid template '%s' of generator '%s': %s", template, it.Name, err)
+	}
+	return &it, nil
+}
+
+func (it Generator) validPartition() error {
+	if it.Partitions > 1 && it.Partition >= it.Partitions || it.Partitions <= 1 && it.Partition != 0 {
+		return fmt.Errorf("partition '%d' of generator '%s' must be less than the number of partitions '%d'", it.Partition, it.Name, it.Partitions)
+	}
+	return nil
+}
+
 func (it Generator) maxHFID() (int64, error) {
-	maxPlus1, err := pow(len(it.Encoding), int(it.Length))
+	maxPlus1, err := it.countTemplateOrEncodingHFIDs()
 	if err != nil {
 		return 0, err
 	}
 	return maxPlus1 - 1, err
 }
 
+// countTemplateOrEncodingHFIDs returns the number of HFIDs at the current Length using the Template if any, or the
+// Encoding otherwise
+func (it Generator) countTemplateOrEncodingHFIDs() (int64, error) {
+	if it.Template == "" {
+		return pow(len(it.Encoding), int(it.Length))
+	}
+	parts, err := parseTemplate(it.Template, it.Encoding)
+	if err != nil {
+		return 0, err
+	}
+	return countTemplateHFIDs(parts, it.Length)
+}
+
 func (it Generator) countHFIDs() (int64, error) {
 	max, err := it.maxHFID()
 
@@ -75,12 +194,59 @@ func (it Generator) countHFIDs() (int64, error) {
 	return max + 1, nil
 }
 
+// countPartitionHFIDs returns the number of HFIDs of the partition at the current Length
+func (it Generator) countPartitionHFIDs() (int64, error) {
+	count, err := it.countHFIDs()
+	if err != nil || it.Partitions <= 1 {
+		return count, err
+	}
+	if count <= int64(it.Partition) {
+		return 0, nil
+	}
+	return (count - int64(it.Partition) + int64(it.Partitions) - 1) / int64(it.Partitions), nil
+}
+
+// drawHFID draws a random HFID of the partition at the current Length
+func (it Generator) drawHFID(r *rand.Rand) (int64, error) {
+	count, err := it.countPartitionHFIDs()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("partition '%d' of generator '%s' has no HFIDs with Length %d", it.Partition, it.Name, it.Length)
+	}
+	hfid := r.Int63n(count)
+	if it.Partitions <= 1 {
+		return hfid, nil
+	}
+	return hfid*int64(it.Partitions) + int64(it.Partition), nil
+}
+
+// inPartition returns true if hfid belongs to the partition
+func (it Generator) inPartition(hfid int64) bool {
+	return it.Partitions <= 1 || hfid%int64(it.Partitions) == int64(it.Partition)
+}
+
 func (it Generator) encodeHFID(n int64) (string, error) {
 	maxN, err := it.maxHFID()
 	if n > maxN {
 		return "", fmt.Errorf("%d is bigger than %d which is the maximum number that can be encoded with encoding '%s' with %d characters", n, maxN, it.Encoding, it.Length)
 	}
 
+	if it.Template != "" {
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", fmt.Errorf("cannot encode negative number %d", n)
+		}
+		parts, err := parseTemplate(it.Template, it.Encoding)
+		if err != nil {
+			return "", err
+		}
+		return it.Prefix + encodeTemplate(parts, n), nil
+	}
+
 	result, err := it.Encoding.Encode(n)
 	if err != nil {
 		return "", err
@@ -101,6 +267,21 @@ func (it Generator) decodeHFID(hfid string) (int64, error) {
 	// Remove the prefix
 	hfid = hfid[len(it.Prefix):]
 
+	if it.Template != "" {
+		parts, err := parseTemplate(it.Template, it.Encoding)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := countTemplateHFIDs(parts, it.Length); err != nil {
+			return 0, err
+		}
+		n, err := decodeTemplate(parts, it.Length, hfid)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode HFID '%s' of type '%s' using template '%s': %s", hfid, it.Name, it.Template, err)
+		}
+		return n, nil
+	}
+
 	// Validate the length
 	if len(hfid) > int(it.Length) || len(hfid) < int(it.MinLength) {
 		return 0, fmt.Errorf("cannot decode HFID '%s' of type '%s' its length is not between [%d, %d]", hfid, it.Name, it.MinLength, it.Length)
@@ -1,8 +1,11 @@
 package hfid
 
 import (
+	"math/rand"
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewGenerator(t *testing.T) {
@@ -24,7 +27,7 @@ func TestNewGenerator(t *testing.T) {
 		{"fails if Length is less than MinLength", args{Name: "a", Encoding: "abc", MinLength: 2, Length: 1}, nil, true},
 		{"fails if Encoding and Length are too large", args{Name: "a", Encoding: NumericEncoding, MinLength: 20, Length: 20}, nil, true},
 		{"fails if Name is empty", args{Name: " ", Encoding: NumericEncoding, MinLength: 2, Length: 2}, nil, true},
-		{"creates Generator with passed parameters", args{"a", "a_", "abc", 1, 3}, &Generator{"a", "a_", "abc", 1, 3}, false},
+		{"creates Generator with passed parameters", args{"a", "a_", "abc", 1, 3}, &Generator{Name: "a", Prefix: "a_", Encoding: "abc", MinLength: 1, Length: 3}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -210,3 +213,66 @@ func TestGenerator_decodeHFID(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_WithPartition(t *testing.T) {
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 1}
+	tests := []struct {
+		name       string
+		partition  uint8
+		partitions uint8
+		wantErr    bool
+	}{
+		{"Partitions the generator", 2, 3, false},
+		{"Accepts a single partition", 0, 1, false},
+		{"Fails when the partition is not less than the number of partitions", 3, 3, true},
+		{"Fails when the partition is set without partitions", 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.WithPartition(tt.partition, tt.partitions)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.partition, got.Partition)
+			assert.Equal(t, tt.partitions, got.Partitions)
+		})
+	}
+}
+
+func TestGenerator_countPartitionHFIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		partition  uint8
+		partitions uint8
+		want       int64
+	}{
+		{"Counts all the HFIDs when not partitioned", 0, 0, 10},
+		{"Counts the HFIDs of the first partition", 0, 3, 4},
+		{"Counts the HFIDs of the last partition", 2, 3, 3},
+		{"Counts no HFIDs when there are more partitions than HFIDs", 11, 12, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := Generator{Name: "a", Encoding: NumericEncoding, Length: 1, Partition: tt.partition, Partitions: tt.partitions}
+			got, err := g.countPartitionHFIDs()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerator_drawHFID(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	g := Generator{Name: "a", Encoding: NumericEncoding, Length: 2, Partition: 2, Partitions: 7}
+	for i := 0; i < 100; i++ {
+		hfid, err := g.drawHFID(r)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), hfid%7)
+		assert.Less(t, hfid, int64(100))
+	}
+
+	_, err := Generator{Name: "a", Encoding: NumericEncoding, Length: 1, Partition: 11, Partitions: 12}.drawHFID(r)
+	assert.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package grpc
+
+import (
+	"context"
+	"gitlab.com/alielgamal/hfid"
+	"gitlab.com/alielgamal/hfid/grpc/hfidpb"
+	"google.golang.org/grpc"
+)
+
+// Client A Struct that wraps an HFIDServiceClient and implements the Source interface provided by HFID. Only the Name of
+// the Generator is sent to the server, which generates the HFID using its own registry.
+type Client struct {
+	hfidpb.HFIDServiceClient
+}
+
+// NewClient creates a Client using a connection to an HFIDService server (e.g. created by grpc.Dial)
+func NewClient(cc grpc.ClientConnInterface) Client {
+	return Client{hfidpb.NewHFIDServiceClient(cc)}
+}
+
+// Generate Implemented using the Generate RPC
+func (c Client) Generate(ctx context.Context, g hfid.Generator) (string, error) {
+	res, err := c.HFIDServiceClient.Generate(ctx, &hfidpb.GenerateRequest{Generator: g.Name})
+	if err != nil {
+		return "", err
+	}
+	return res.Id, nil
+}
+
+var _ hfid.Source = Client{}
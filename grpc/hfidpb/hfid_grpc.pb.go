@@ -0,0 +1,223 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: grpc/hfidpb/hfid.proto
+
+package hfidpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// HFIDServiceClient is the client API for HFIDService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HFIDServiceClient interface {
+	// Generate generates a single HFID
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error)
+	// GenerateBatch generates count HFIDs
+	GenerateBatch(ctx context.Context, in *GenerateBatchRequest, opts ...grpc.CallOption) (*GenerateBatchResponse, error)
+	// Parse decodes an HFID back into the number it encodes using the current length of the generator
+	Parse(ctx context.Context, in *ParseRequest, opts ...grpc.CallOption) (*ParseResponse, error)
+	// ListGenerators lists the registered generators along with their current length and the estimated count of
+	// generated HFIDs
+	ListGenerators(ctx context.Context, in *ListGeneratorsRequest, opts ...grpc.CallOption) (*ListGeneratorsResponse, error)
+}
+
+type hFIDServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHFIDServiceClient(cc grpc.ClientConnInterface) HFIDServiceClient {
+	return &hFIDServiceClient{cc}
+}
+
+func (c *hFIDServiceClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error) {
+	out := new(GenerateResponse)
+	err := c.cc.Invoke(ctx, "/hfid.v1.HFIDService/Generate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hFIDServiceClient) GenerateBatch(ctx context.Context, in *GenerateBatchRequest, opts ...grpc.CallOption) (*GenerateBatchResponse, error) {
+	out := new(GenerateBatchResponse)
+	err := c.cc.Invoke(ctx, "/hfid.v1.HFIDService/GenerateBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hFIDServiceClient) Parse(ctx context.Context, in *ParseRequest, opts ...grpc.CallOption) (*ParseResponse, error) {
+	out := new(ParseResponse)
+	err := c.cc.Invoke(ctx, "/hfid.v1.HFIDService/Parse", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hFIDServiceClient) ListGenerators(ctx context.Context, in *ListGeneratorsRequest, opts ...grpc.CallOption) (*ListGeneratorsResponse, error) {
+	out := new(ListGeneratorsResponse)
+	err := c.cc.Invoke(ctx, "/hfid.v1.HFIDService/ListGenerators", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HFIDServiceServer is the server API for HFIDService service.
+// All implementations must embed UnimplementedHFIDServiceServer
+// for forward compatibility
+type HFIDServiceServer interface {
+	// Generate generates a single HFID
+	Generate(context.Context, *GenerateRequest) (*GenerateResponse, error)
+	// GenerateBatch generates count HFIDs
+	GenerateBatch(context.Context, *GenerateBatchRequest) (*GenerateBatchResponse, error)
+	// Parse decodes an HFID back into the number it encodes using the current length of the generator
+	Parse(context.Context, *ParseRequest) (*ParseResponse, error)
+	// ListGenerators lists the registered generators along with their current length and the estimated count of
+	// generated HFIDs
+	ListGenerators(context.Context, *ListGeneratorsRequest) (*ListGeneratorsResponse, error)
+	mustEmbedUnimplementedHFIDServiceServer()
+}
+
+// UnimplementedHFIDServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedHFIDServiceServer struct {
+}
+
+func (UnimplementedHFIDServiceServer) Generate(context.Context, *GenerateRequest) (*GenerateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedHFIDServiceServer) GenerateBatch(context.Context, *GenerateBatchRequest) (*GenerateBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateBatch not implemented")
+}
+func (UnimplementedHFIDServiceServer) Parse(context.Context, *ParseRequest) (*ParseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Parse not implemented")
+}
+func (UnimplementedHFIDServiceServer) ListGenerators(context.Context, *ListGeneratorsRequest) (*ListGeneratorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGenerators not implemented")
+}
+func (UnimplementedHFIDServiceServer) mustEmbedUnimplementedHFIDServiceServer() {}
+
+// UnsafeHFIDServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HFIDServiceServer will
+// result in compilation errors.
+type UnsafeHFIDServiceServer interface {
+	mustEmbedUnimplementedHFIDServiceServer()
+}
+
+func RegisterHFIDServiceServer(s grpc.ServiceRegistrar, srv HFIDServiceServer) {
+	s.RegisterService(&HFIDService_ServiceDesc, srv)
+}
+
+func _HFIDService_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HFIDServiceServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hfid.v1.HFIDService/Generate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HFIDServiceServer).Generate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HFIDService_GenerateBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HFIDServiceServer).GenerateBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hfid.v1.HFIDService/GenerateBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HFIDServiceServer).GenerateBatch(ctx, req.(*GenerateBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HFIDService_Parse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HFIDServiceServer).Parse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hfid.v1.HFIDService/Parse",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HFIDServiceServer).Parse(ctx, req.(*ParseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HFIDService_ListGenerators_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGeneratorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HFIDServiceServer).ListGenerators(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hfid.v1.HFIDService/ListGenerators",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HFIDServiceServer).ListGenerators(ctx, req.(*ListGeneratorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HFIDService_ServiceDesc is the grpc.ServiceDesc for HFIDService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HFIDService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hfid.v1.HFIDService",
+	HandlerType: (*HFIDServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Generate",
+			Handler:    _HFIDService_Generate_Handler,
+		},
+		{
+			MethodName: "GenerateBatch",
+			Handler:    _HFIDService_GenerateBatch_Handler,
+		},
+		{
+			MethodName: "Parse",
+			Handler:    _HFIDService_Parse_Handler,
+		},
+		{
+			MethodName: "ListGenerators",
+			Handler:    _HFIDService_ListGenerators_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "grpc/hfidpb/hfid.proto",
+}
@@ -0,0 +1,153 @@
+// Package grpc provides a gRPC server that generates HFIDs using any GeneratorStore and a client that implements the
+// Source interface provided by HFID, so that callers can swap between local and remote generation transparently.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"gitlab.com/alielgamal/hfid"
+	"gitlab.com/alielgamal/hfid/grpc/hfidpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+)
+
+const defaultMaxBatch = 1000
+
+// Server A Struct that implements the HFIDService using a GeneratorStore. Only the generators found in the Generators
+// registry can be used, keyed by their name. Register it using hfidpb.RegisterHFIDServiceServer.
+type Server struct {
+	hfidpb.UnimplementedHFIDServiceServer
+
+	Store      hfid.GeneratorStore
+	Generators map[string]hfid.Generator
+
+	// MaxBatch is the maximum number of HFIDs that can be generated in a single GenerateBatch call. 1000 is used when
+	// zero.
+	MaxBatch int
+}
+
+// Generate Implemented using HFID
+func (s Server) Generate(ctx context.Context, req *hfidpb.GenerateRequest) (*hfidpb.GenerateResponse, error) {
+	g, err := s.generator(req.Generator)
+	if err != nil {
+		return nil, err
+	}
+	id, err := hfid.HFID(ctx, g, s.Store)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &hfidpb.GenerateResponse{Id: id}, nil
+}
+
+// GenerateBatch Implemented by calling HFID Count times
+func (s Server) GenerateBatch(ctx context.Context, req *hfidpb.GenerateBatchRequest) (*hfidpb.GenerateBatchResponse, error) {
+	g, err := s.generator(req.Generator)
+	if err != nil {
+		return nil, err
+	}
+	if req.Count < 1 || int(req.Count) > s.maxBatch() {
+		return nil, status.Errorf(codes.InvalidArgument, "count must be between 1 and %d", s.maxBatch())
+	}
+
+	result := &hfidpb.GenerateBatchResponse{Ids: make([]string, 0, req.Count)}
+	for i := int32(0); i < req.Count; i++ {
+		id, err := hfid.HFID(ctx, g, s.Store)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		result.Ids = append(result.Ids, id)
+	}
+	return result, nil
+}
+
+// Parse Implemented using hfid.Parse with the Generator found in the store since it may have grown since it was
+// registered, or the registered one if it hasn't been stored yet. The registered partition and template are used since stores don't persist them.
+func (s Server) Parse(ctx context.Context, req *hfidpb.ParseRequest) (*hfidpb.ParseResponse, error) {
+	g, err := s.generator(req.Generator)
+	if err != nil {
+		return nil, err
+	}
+	storedG, _, err := s.storedGenerator(ctx, g)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	storedG.Partitions, storedG.Partition, storedG.Template = g.Partitions, g.Partition, g.Template
+	n, err := hfid.Parse(storedG, req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &hfidpb.ParseResponse{Number: n}, nil
+}
+
+// ListGenerators Implemented by fetching every registered generator from the store sorted by name. The registered
+// generators that haven't been stored yet are listed as is.
+func (s Server) ListGenerators(ctx context.Context, _ *hfidpb.ListGeneratorsRequest) (*hfidpb.ListGeneratorsResponse, error) {
+	names := make([]string, 0, len(s.Generators))
+	for name := range s.Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &hfidpb.ListGeneratorsResponse{Generators: make([]*hfidpb.Generator, 0, len(names))}
+	for _, name := range names {
+		g, c, err := s.storedGenerator(ctx, s.Generators[name])
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		result.Generators = append(result.Generators, &hfidpb.Generator{
+			Name:      g.Name,
+			Prefix:    g.Prefix,
+			Encoding:  string(g.Encoding),
+			MinLength: uint32(g.MinLength),
+			Length:    uint32(g.Length),
+			Count:     c,
+		})
+	}
+	return result, nil
+}
+
+func (s Server) generator(name string) (hfid.Generator, error) {
+	g, ok := s.Generators[name]
+	if !ok {
+		return g, status.Errorf(codes.NotFound, "generator '%s' not found", name)
+	}
+	return g, nil
+}
+
+// storedGenerator returns the stored version of the registered generator g along with the estimate number of HFIDs
+// generated using it. The generator is fetched without being created when the store implements hfid.GeneratorAdmin, in
+// which case g is returned as is if it hasn't been stored yet.
+func (s Server) storedGenerator(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	admin, ok := s.Store.(hfid.GeneratorAdmin)
+	if !ok {
+		return s.Store.InsertOrGet(ctx, g)
+	}
+	storedG, c, found, err := admin.Get(ctx, g.Name)
+	if err != nil || !found {
+		return g, 0, err
+	}
+	return storedG, c, nil
+}
+
+func (s Server) maxBatch() int {
+	if s.MaxBatch > 0 {
+		return s.MaxBatch
+	}
+	return defaultMaxBatch
+}
+
+// toStatus converts errors returned by the store to a gRPC status while keeping context and rate limit errors
+// distinguishable
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, hfid.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
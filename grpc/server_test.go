@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"gitlab.com/alielgamal/hfid/grpc/hfidpb"
+	hfidredis "gitlab.com/alielgamal/hfid/redis"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+var userG = hfid.Generator{Name: "User", Prefix: "U-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 3}
+
+// startServer serves a Server backed by miniredis over an in-memory connection and returns a Client connected to it.
+// The HFIDs of userG are tracked using a Set since the HyperLogLog may rarely report a duplicate HFID as new.
+func startServer(t *testing.T) Client {
+	return startServerUsing(t, miniredis.RunT(t))
+}
+
+// startServerUsing is like startServer but the Server is backed by mr
+func startServerUsing(t *testing.T, mr *miniredis.Miniredis) Client {
+	uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer()
+	hfidpb.RegisterHFIDServiceServer(gs, Server{
+		Store: hfidredis.GeneratorStore{
+			UniversalClient: uc,
+			Memberships:     map[string]hfidredis.Membership{userG.Name: {Type: hfidredis.SetMembership}},
+		},
+		Generators: map[string]hfid.Generator{userG.Name: userG},
+		MaxBatch:   10,
+	})
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+	return NewClient(cc)
+}
+
+func TestServer_Generate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Generates an HFID using the Source interface", func(t *testing.T) {
+		var s hfid.Source = startServer(t)
+		id, err := s.Generate(ctx, userG)
+		assert.NoError(t, err)
+		assert.Regexp(t, "^U-[0-9]{3}$", id)
+	})
+
+	t.Run("Fails if the generator is not registered", func(t *testing.T) {
+		_, err := startServer(t).Generate(ctx, hfid.Generator{Name: "Unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestServer_GenerateBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Generates a batch of unique HFIDs", func(t *testing.T) {
+		res, err := startServer(t).GenerateBatch(ctx, &hfidpb.GenerateBatchRequest{Generator: userG.Name, Count: 10})
+		assert.NoError(t, err)
+		assert.Len(t, res.Ids, 10)
+		unique := map[string]bool{}
+		for _, id := range res.Ids {
+			unique[id] = true
+		}
+		assert.Len(t, unique, 10)
+	})
+
+	t.Run("Fails if the count exceeds the maximum batch", func(t *testing.T) {
+		_, err := startServer(t).GenerateBatch(ctx, &hfidpb.GenerateBatchRequest{Generator: userG.Name, Count: 11})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServer_Parse(t *testing.T) {
+	ctx := context.Background()
+	c := startServer(t)
+
+	res, err := c.Parse(ctx, &hfidpb.ParseRequest{Generator: userG.Name, Id: "U-042"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), res.Number)
+
+	_, err = c.Parse(ctx, &hfidpb.ParseRequest{Generator: userG.Name, Id: "X-042"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_readOnly(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := startServerUsing(t, mr)
+
+	_, err := c.Parse(ctx, &hfidpb.ParseRequest{Generator: userG.Name, Id: "U-042"})
+	assert.NoError(t, err)
+	res, err := c.ListGenerators(ctx, &hfidpb.ListGeneratorsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), res.Generators[0].Length)
+	assert.Empty(t, mr.Keys(), "parsing and listing must not create the generators")
+}
+
+func TestServer_ListGenerators(t *testing.T) {
+	ctx := context.Background()
+	c := startServer(t)
+	_, err := c.GenerateBatch(ctx, &hfidpb.GenerateBatchRequest{Generator: userG.Name, Count: 2})
+	assert.NoError(t, err)
+
+	res, err := c.ListGenerators(ctx, &hfidpb.ListGeneratorsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Generators, 1)
+	assert.Equal(t, "User", res.Generators[0].Name)
+	assert.Equal(t, uint32(3), res.Generators[0].Length)
+	assert.Equal(t, int64(2), res.Generators[0].Count)
+}
+
+func Test_toStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"Rate limited", fmt.Errorf("generator 'User' is %w", hfid.ErrRateLimited), codes.ResourceExhausted},
+		{"Canceled", context.Canceled, codes.Canceled},
+		{"Deadline exceeded", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"Store error", fmt.Errorf("mock error"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, status.Code(toStatus(tt.err)))
+		})
+	}
+}
@@ -4,57 +4,302 @@ package hfid
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
 	"time"
 )
 
 var defaultRand = *rand.New(rand.NewSource(time.Now().UnixNano()))
 
+// growthRatio The ratio of the HFIDs at the current Length that can be generated before the Length is increased
+const growthRatio = 0.5
+
+// Source An interface for anything that generates HFIDs using a Generator. It allows callers to swap between generating
+// HFIDs locally using a StoreSource and remotely (e.g. using the gRPC client) transparently.
+type Source interface {
+	Generate(ctx context.Context, g Generator) (string, error)
+}
+
+// StoreSource A Source that generates HFIDs locally by calling HFID with its Store using its Options
+type StoreSource struct {
+	Store   GeneratorStore
+	Options Options
+}
+
+// Generate calls HFID using the Store and the Options of the StoreSource
+func (s StoreSource) Generate(ctx context.Context, g Generator) (string, error) {
+	return s.Options.HFID(ctx, g, s.Store)
+}
+
+// Options Customizes how HFIDs are generated. The zero value generates HFIDs the same way as the HFID function.
+type Options struct {
+	// Rand is the random source used to draw HFIDs. A non-deterministic Rand object is used when nil.
+	Rand *rand.Rand
+
+	// Instrumenter observes the generation of HFIDs (e.g. to export metrics). Nothing is observed when nil.
+	Instrumenter Instrumenter
+
+	// Logger logs structured events for the increases of the generator length, retries, exhausted generators and store
+	// failures. A *slog.Logger can be used. Nothing is logged when nil.
+	Logger Logger
+
+	// Filter rejects drawn HFIDs before they are added to the store (e.g. HFIDs spelling offensive words). Another HFID
+	// is drawn instead of a rejected one. Nothing is rejected when nil.
+	Filter Filter
+
+	// Limiter is consulted before generating each HFID using Limit. An error wrapping ErrRateLimited is returned when
+	// the generator has no token left. HFIDs are not limited when nil.
+	Limiter Limiter
+	Limit   Limit
+}
+
 // HFID generates a new HFID. If you would like to have deterministic way of generating HFIDs, pass a Rand object,
 // otherwise a non-deterministic Rand object will be used. It is recommended to wrap calls to this function with a
-// circuit breaker that falls back to a normal UUID when open.
+// circuit breaker that falls back to a random ID when open (e.g. a Fallback wrapping a StoreSource). If the
+// GeneratorStore implements Claimer, each attempt is done in a single Claim call. Use Options to customize the
+// generation further.
 func HFID(ctx context.Context, g Generator, s GeneratorStore, dr ...rand.Rand) (string, error) {
-	// Fetch or create the generator
-	g, c, err := s.InsertOrGet(ctx, g)
-	if err != nil {
+	var o Options
+	if len(dr) > 0 {
+		o.Rand = &dr[0]
+	}
+	return o.HFID(ctx, g, s)
+}
+
+// HFID generates a new HFID using the Options. See HFID function for details.
+func (o Options) HFID(ctx context.Context, g Generator, s GeneratorStore) (string, error) {
+	// Prepare a random source
+	if o.Rand == nil {
+		o.Rand = &defaultRand
+	}
+	if o.Instrumenter == nil {
+		o.Instrumenter = nopInstrumenter{}
+	}
+	if o.Logger == nil {
+		o.Logger = nopLogger{}
+	}
+	in, l := o.Instrumenter, o.Logger
+
+	if err := g.validPartition(); err != nil {
 		return "", err
 	}
 
-	// Checking if we need to increase the length of the generator
-	maxC, err := g.countHFIDs()
-	if err != nil {
+	start := time.Now()
+	if err := o.allow(ctx, g, in, l); err != nil {
+		in.Generated(g.Name, time.Since(start), 0, err)
 		return "", err
 	}
-	if c+1 > int64(float64(maxC)*0.5) {
-		g.Length++
-		err = s.Upsert(ctx, g)
+
+	var retries int
+	var result string
+	var err error
+	// The store increases the Length of the generator when claiming without knowing about the partitions or the
+	// template, hence it is not used for partitioned or templated generators
+	if c, ok := s.(Claimer); ok && g.Partitions <= 1 && g.Template == "" {
+		result, retries, err = claimHFID(ctx, g, c, o)
+	} else {
+		result, retries, err = addHFID(ctx, g, s, o)
+	}
+	in.Generated(g.Name, time.Since(start), retries, err)
+	return result, err
+}
+
+// allow takes a token from the Limiter if any
+func (o Options) allow(ctx context.Context, g Generator, in Instrumenter, l Logger) error {
+	if o.Limiter == nil {
+		return nil
+	}
+	allowed, err := o.Limiter.Allow(ctx, g.Name, o.Limit)
+	if err != nil {
+		in.StoreError(g.Name, "Allow", err)
+		logStoreError(ctx, l, g, "Allow", err)
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("generator '%s' is %w", g.Name, ErrRateLimited)
+	}
+	return nil
+}
+
+// draw draws an HFID for the current Length of the generator that isn't rejected by the Filter. It returns the drawn
+// number along with the encoded HFID.
+func (o Options) draw(ctx context.Context, g Generator) (int64, string, error) {
+	for rejections := 0; ; rejections++ {
+		hfid, err := g.drawHFID(o.Rand)
 		if err != nil {
-			return "", err
+			return 0, "", err
+		}
+		result, err := g.encodeHFID(hfid)
+		if err != nil || o.Filter == nil || !o.Filter.Reject(result[len(g.Prefix):]) {
+			return hfid, result, err
+		}
+		if rejections+1 >= maxRejections {
+			return 0, "", fmt.Errorf("%d HFIDs drawn for generator '%s' have been rejected by the filter", maxRejections, g.Name)
 		}
+		o.Logger.DebugContext(ctx, "hfid: drawn HFID has been rejected by the filter, drawing another one",
+			"generator", g.Name, "length", g.Length, "rejections", rejections+1)
 	}
+}
 
-	// Prepare a random source
-	var r rand.Rand
-	if len(dr) == 0 {
-		r = defaultRand
-	} else {
-		r = dr[0]
+// addHFID prepares the generator then draws HFIDs until one is added to the store. It returns the HFID along with the
+// number of drawn HFIDs that had been generated before.
+func addHFID(ctx context.Context, g Generator, s GeneratorStore, o Options) (string, int, error) {
+	in, l := o.Instrumenter, o.Logger
+	g, err := prepareGenerator(ctx, g, s, o)
+	if err != nil {
+		return "", 0, err
 	}
 
 	// Generate valid HFID
-	max, err := g.maxHFID()
-	if err != nil {
-		return "", err
+	if _, err := g.maxHFID(); err != nil {
+		logExhausted(ctx, l, g, err)
+		return "", 0, err
 	}
-	var hfid int64
-	for {
-		hfid = r.Int63n(max + 1)
+	for retries := 0; ; retries++ {
+		hfid, result, err := o.draw(ctx, g)
+		if err != nil {
+			return "", retries, err
+		}
 		isNew, err := s.Add(ctx, hfid, g.Name)
 		if err != nil {
-			return "", err
+			in.StoreError(g.Name, "Add", err)
+			logStoreError(ctx, l, g, "Add", err)
+			return "", retries, err
+		}
+		in.Added(g.Name, isNew)
+		if isNew {
+			return result, retries, nil
+		}
+		l.DebugContext(ctx, "hfid: drawn HFID has been generated before, drawing another one", "generator", g.Name,
+			"length", g.Length, "retries", retries+1)
+	}
+}
+
+// prepareGenerator fetches or creates the generator and increases its length if 50% of the HFIDs at the current length
+// have been generated. If the store reports a ConflictError while increasing the length, the generator is fetched again.
+func prepareGenerator(ctx context.Context, g Generator, s GeneratorStore, o Options) (Generator, error) {
+	in, l := o.Instrumenter, o.Logger
+	for {
+		// Fetch or create the generator
+		storedG, c, err := s.InsertOrGet(ctx, g)
+		if err != nil {
+			in.StoreError(g.Name, "InsertOrGet", err)
+			logStoreError(ctx, l, g, "InsertOrGet", err)
+			return storedG, err
+		}
+		// The partition and the template are properties of the caller rather than the store
+		storedG.Partitions, storedG.Partition, storedG.Template = g.Partitions, g.Partition, g.Template
+
+		// Checking if we need to increase the length of the generator. The store of a partitioned generator only counts
+		// the HFIDs of the partition.
+		maxC, err := storedG.countPartitionHFIDs()
+		if err != nil {
+			logExhausted(ctx, l, storedG, err)
+			return storedG, err
+		}
+		fillRatio := float64(c) / float64(maxC)
+		in.FillRatio(g.Name, fillRatio)
+		if c+1 <= int64(float64(maxC)*growthRatio) {
+			return storedG, nil
+		}
+		storedG.Length++
+		err = s.Upsert(ctx, storedG)
+		var conflictErr ConflictError
+		if errors.As(err, &conflictErr) {
+			l.DebugContext(ctx, "hfid: generator has been updated concurrently, fetching it again", "generator", g.Name,
+				"error", err)
+			continue
+		}
+		if err != nil {
+			in.StoreError(g.Name, "Upsert", err)
+			logStoreError(ctx, l, g, "Upsert", err)
+			return storedG, err
+		}
+		in.Grown(g.Name, storedG.Length)
+		l.InfoContext(ctx, "hfid: increased the length of the generator", "generator", g.Name,
+			"previousLength", storedG.Length-1, "length", storedG.Length, "fillRatio", fillRatio)
+		return storedG, nil
+	}
+}
+
+// claimHFID draws an HFID for the current Length of the generator and claims it. If the HFID was a duplicate or the
+// store reported a different Length or Encoding, a new HFID is drawn using the Generator returned by the store. It returns the HFID
+// along with the number of drawn HFIDs that have not been claimed.
+func claimHFID(ctx context.Context, g Generator, c Claimer, o Options) (string, int, error) {
+	in, l := o.Instrumenter, o.Logger
+	for retries := 0; ; retries++ {
+		if _, err := g.maxHFID(); err != nil {
+			logExhausted(ctx, l, g, err)
+			return "", retries, err
+		}
+		hfid, _, err := o.draw(ctx, g)
+		if err != nil {
+			return "", retries, err
+		}
+		res, err := c.Claim(ctx, g, hfid)
+		if err != nil {
+			in.StoreError(g.Name, "Claim", err)
+			logStoreError(ctx, l, g, "Claim", err)
+			return "", retries, err
+		}
+		storedG, isNew := res.Generator, res.Added
+		reportClaim(ctx, g, res, o)
+		drawnForStoredG := storedG.Length == g.Length && storedG.Encoding == g.Encoding
+		if drawnForStoredG {
+			// hfid has been drawn for the right Length and Encoding, hence it has been added if it was new
+			in.Added(g.Name, isNew)
 		}
 		if isNew {
-			return g.encodeHFID(hfid)
+			result, err := storedG.encodeHFID(hfid)
+			return result, retries, err
 		}
+		if !drawnForStoredG {
+			l.DebugContext(ctx, "hfid: drawn HFID has been drawn for a different length or encoding, drawing another one",
+				"generator", g.Name, "length", g.Length, "storedLength", storedG.Length, "retries", retries+1)
+		} else {
+			l.DebugContext(ctx, "hfid: drawn HFID has been generated before, drawing another one", "generator", g.Name,
+				"length", g.Length, "retries", retries+1)
+		}
+		g = storedG
+	}
+}
+
+// reportClaim reports the fill ratio of the generator before the claim along with its new Length if the claim increased
+// it, the same way prepareGenerator does
+func reportClaim(ctx context.Context, g Generator, res ClaimResult, o Options) {
+	in, l := o.Instrumenter, o.Logger
+	previousG := res.Generator
+	if res.Grown {
+		previousG.Length--
+	}
+	var fillRatio float64
+	if maxC, err := previousG.countPartitionHFIDs(); err == nil {
+		fillRatio = float64(res.Count) / float64(maxC)
+		in.FillRatio(g.Name, fillRatio)
+	}
+	if res.Grown {
+		in.Grown(g.Name, res.Generator.Length)
+		l.InfoContext(ctx, "hfid: increased the length of the generator", "generator", g.Name,
+			"previousLength", previousG.Length, "length", res.Generator.Length, "fillRatio", fillRatio)
+	}
+}
+
+// Parse decodes hfid that has been generated using g back into the number it encodes. An error is returned if hfid
+// doesn't start with the Prefix of g, its length isn't between MinLength and Length or it contains characters that are
+// not part of the Encoding. A FallbackError is returned if hfid has been generated by FallbackHFID. If g is partitioned,
+// an error is returned if hfid doesn't belong to the partition of g. If g has a Template, an error is returned if hfid
+// doesn't match it instead of checking the length and the Encoding.
+func Parse(g Generator, hfid string) (int64, error) {
+	if isFallbackHFID(g, hfid) {
+		return 0, FallbackError{Name: g.Name, HFID: hfid}
+	}
+	n, err := g.decodeHFID(hfid)
+	if err != nil {
+		return 0, err
+	}
+	if !g.inPartition(n) {
+		return 0, fmt.Errorf("HFID '%s' of type '%s' doesn't belong to partition '%d' of '%d'", hfid, g.Name, g.Partition, g.Partitions)
 	}
+	return n, nil
 }
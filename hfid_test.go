@@ -87,7 +87,7 @@ func TestHFID(t *testing.T) {
 
 	t.Run("Generates HFID using existing generator length", func(t *testing.T) {
 		mgs := NewMockGeneratorStore(t)
-		mgs.On("InsertOrGet", ctx, *g).Return(Generator{"a", "", NumericEncoding, 0, 3}, int64(0), nil)
+		mgs.On("InsertOrGet", ctx, *g).Return(Generator{Name: "a", Encoding: NumericEncoding, Length: 3}, int64(0), nil)
 		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
 
 		hfid, err := HFID(ctx, *g, mgs)
@@ -121,4 +121,153 @@ func TestHFID(t *testing.T) {
 		assert.NoError(t, err)
 		mgs.AssertExpectations(t)
 	})
+
+	t.Run("Re-reads the generator when increasing the length conflicts with another update", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		newG := *g
+		newG.Length++
+		mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(6), nil).Once()
+		mgs.On("Upsert", ctx, newG).Return(ConflictError{Name: g.Name, Reason: "mock conflict"}).Once()
+		mgs.On("InsertOrGet", ctx, *g).Return(newG, int64(6), nil).Once()
+		mgs.On("Add", ctx, int64(10), g.Name).Return(true, nil).Once()
+
+		hfid, err := HFID(ctx, *g, mgs, *rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Equal(t, "10", hfid)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Claims HFIDs in a single call when the store is a Claimer", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		mcs.On("Claim", ctx, *g, int64(0)).Return(ClaimResult{Generator: *g, Added: true}, nil).Once()
+
+		hfid, err := HFID(ctx, *g, mcs, *rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Equal(t, "0", hfid)
+		mcs.AssertExpectations(t)
+	})
+
+	t.Run("Redraws using the claimed generator when the claim fails", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		longerG := *g
+		longerG.Length++
+		mcs.On("Claim", ctx, *g, int64(0)).Return(ClaimResult{Generator: longerG}, nil).Once()
+		mcs.On("Claim", ctx, longerG, mock.Anything).Return(ClaimResult{Generator: longerG, Added: true}, nil).Once()
+
+		hfid, err := HFID(ctx, *g, mcs, *rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(hfid))
+		mcs.AssertExpectations(t)
+	})
+
+	t.Run("Redraws using the claimed generator when the Encoding differs", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		storedG := *g
+		storedG.Encoding = DefaultEncoding
+		mcs.On("Claim", ctx, *g, int64(0)).Return(ClaimResult{Generator: storedG}, nil).Once()
+		mcs.On("Claim", ctx, storedG, mock.Anything).Return(ClaimResult{Generator: storedG, Added: true}, nil).Once()
+
+		hfid, err := HFID(ctx, *g, mcs, *rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(hfid))
+		mcs.AssertExpectations(t)
+	})
+
+	t.Run("fails when the Claimer returns an error", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		mcs.On("Claim", ctx, *g, mock.Anything).Return(ClaimResult{Generator: *g}, fmt.Errorf("mock error"))
+
+		_, err := HFID(ctx, *g, mcs)
+		assert.Error(t, err)
+		mcs.AssertExpectations(t)
+	})
+}
+
+func TestParse(t *testing.T) {
+	g := Generator{Name: "Test", Prefix: "T-", Encoding: NumericEncoding, MinLength: 1, Length: 3}
+	tests := []struct {
+		name    string
+		hfid    string
+		want    int64
+		wantErr bool
+	}{
+		{"Decodes an HFID generated by the generator", "T-042", 42, false},
+		{"Decodes an HFID shorter than the Length", "T-7", 7, false},
+		{"Fails if the prefix is different", "X-042", 0, true},
+		{"Fails if the HFID is longer than the Length", "T-0042", 0, true},
+		{"Fails if the HFID has characters outside the Encoding", "T-04A", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(g, tt.hfid)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStoreSource_Generate(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewGenerator("a", "A-", NumericEncoding, 0, 1)
+	assert.NoError(t, err)
+
+	mgs := NewMockGeneratorStore(t)
+	mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(0), nil)
+	mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
+
+	var s Source = StoreSource{Store: mgs}
+	got, err := s.Generate(ctx, *g)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^A-[0-9]$", got)
+	mgs.AssertExpectations(t)
+}
+
+func TestHFID_partitioned(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewGenerator("a", "", NumericEncoding, 1, 2)
+	assert.NoError(t, err)
+	pg, err := g.WithPartition(1, 4)
+	assert.NoError(t, err)
+
+	t.Run("Generates HFIDs of the partition only", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		mcs.On("InsertOrGet", ctx, *pg).Return(*g, int64(0), nil)
+		mcs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
+
+		for i := 0; i < 20; i++ {
+			id, err := HFID(ctx, *pg, mcs, *rand.New(rand.NewSource(int64(i))))
+			assert.NoError(t, err)
+			n, err := Parse(*pg, id)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n%4)
+		}
+		mcs.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Increases the Length when 50% of the partition has been generated", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		newG := *pg
+		newG.Length++
+		mgs.On("InsertOrGet", ctx, *pg).Return(*g, int64(12), nil)
+		mgs.On("Upsert", ctx, newG).Return(nil)
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
+
+		id, err := HFID(ctx, *pg, mgs)
+		assert.NoError(t, err)
+		assert.Len(t, id, 3)
+	})
+
+	t.Run("Fails to parse HFIDs of other partitions", func(t *testing.T) {
+		_, err := Parse(*pg, "12")
+		assert.Error(t, err)
+	})
+
+	t.Run("Fails with an invalid partition", func(t *testing.T) {
+		_, err := HFID(ctx, Generator{Name: "a", Encoding: NumericEncoding, Length: 1, Partition: 4, Partitions: 4}, NewMockGeneratorStore(t))
+		assert.Error(t, err)
+	})
 }
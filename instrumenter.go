@@ -0,0 +1,39 @@
+package hfid
+
+import "time"
+
+// Instrumenter An interface to observe the generation of HFIDs (e.g. to export metrics) that can be set in Options. The
+// methods are called synchronously while generating HFIDs, hence implementations must be fast and safe for concurrent
+// use.
+type Instrumenter interface {
+	// Generated is called once per HFID call with the time it took, the number of drawn HFIDs that had been generated
+	// before (or couldn't be claimed) and the error returned, if any.
+	Generated(gName string, d time.Duration, retries int, err error)
+
+	// Added is called with the outcome of adding a drawn HFID to the store: true if it was new, false otherwise.
+	Added(gName string, isNew bool)
+
+	// Grown is called with the new Length after increasing the Length of a generator, including when a Claimer store
+	// increases it while claiming an HFID.
+	Grown(gName string, length uint8)
+
+	// FillRatio is called with the estimated ratio of the generated HFIDs to all the HFIDs that can be generated at the
+	// current Length of a generator, before increasing it if needed.
+	FillRatio(gName string, ratio float64)
+
+	// StoreError is called when a call to the store fails. op is the name of the GeneratorStore, Claimer or Limiter
+	// method.
+	StoreError(gName string, op string, err error)
+}
+
+type nopInstrumenter struct{}
+
+func (nopInstrumenter) Generated(string, time.Duration, int, error) {}
+
+func (nopInstrumenter) Added(string, bool) {}
+
+func (nopInstrumenter) Grown(string, uint8) {}
+
+func (nopInstrumenter) FillRatio(string, float64) {}
+
+func (nopInstrumenter) StoreError(string, string, error) {}
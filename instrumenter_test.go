@@ -0,0 +1,91 @@
+package hfid
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOptions_Instrumenter(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewGenerator("a", "", NumericEncoding, 0, 1)
+	assert.NoError(t, err)
+
+	t.Run("Reports retries, Add outcomes, fill ratio and growth", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		newG := *g
+		newG.Length++
+		mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(6), nil)
+		mgs.On("Upsert", ctx, newG).Return(nil)
+		mgs.On("Add", ctx, int64(10), g.Name).Return(false, nil).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+
+		mi := NewMockInstrumenter(t)
+		mi.On("FillRatio", g.Name, 0.6).Once()
+		mi.On("Grown", g.Name, uint8(2)).Once()
+		mi.On("Added", g.Name, false).Once()
+		mi.On("Added", g.Name, true).Once()
+		mi.On("Generated", g.Name, mock.Anything, 1, nil).Once()
+
+		o := Options{Rand: rand.New(rand.NewSource(1)), Instrumenter: mi}
+		_, err := o.HFID(ctx, *g, mgs)
+		assert.NoError(t, err)
+		mgs.AssertExpectations(t)
+		mi.AssertExpectations(t)
+	})
+
+	t.Run("Reports store errors", func(t *testing.T) {
+		storeErr := fmt.Errorf("mock error")
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(0), storeErr)
+
+		mi := NewMockInstrumenter(t)
+		mi.On("StoreError", g.Name, "InsertOrGet", storeErr).Once()
+		mi.On("Generated", g.Name, mock.Anything, 0, storeErr).Once()
+
+		_, err := Options{Instrumenter: mi}.HFID(ctx, *g, mgs)
+		assert.Error(t, err)
+		mi.AssertExpectations(t)
+	})
+
+	t.Run("Reports claims that were drawn for the stored Length only", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		storedG := *g
+		storedG.Length = 2
+		mcs.On("Claim", ctx, *g, int64(0)).Return(ClaimResult{Generator: storedG}, nil).Once()
+		mcs.On("Claim", ctx, storedG, mock.Anything).Return(ClaimResult{Generator: storedG, Added: true}, nil).Once()
+
+		mi := NewMockInstrumenter(t)
+		mi.On("FillRatio", g.Name, 0.0).Twice()
+		mi.On("Added", g.Name, true).Once()
+		mi.On("Generated", g.Name, mock.Anything, 1, nil).Once()
+
+		_, err := Options{Rand: rand.New(rand.NewSource(1)), Instrumenter: mi}.HFID(ctx, *g, mcs)
+		assert.NoError(t, err)
+		mcs.AssertExpectations(t)
+		mi.AssertExpectations(t)
+	})
+	t.Run("Reports the fill ratio and growth of claimed generators", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		newG := *g
+		newG.Length++
+		mcs.On("Claim", ctx, *g, int64(0)).Return(ClaimResult{Generator: newG, Count: 6, Grown: true}, nil).Once()
+		mcs.On("Claim", ctx, newG, mock.Anything).Return(ClaimResult{Generator: newG, Count: 6, Added: true}, nil).Once()
+
+		mi := NewMockInstrumenter(t)
+		mi.On("FillRatio", g.Name, 0.6).Once()
+		mi.On("Grown", g.Name, uint8(2)).Once()
+		mi.On("FillRatio", g.Name, 0.06).Once()
+		mi.On("Added", g.Name, true).Once()
+		mi.On("Generated", g.Name, mock.Anything, 1, nil).Once()
+
+		_, err := Options{Rand: rand.New(rand.NewSource(1)), Instrumenter: mi}.HFID(ctx, *g, mcs)
+		assert.NoError(t, err)
+		mcs.AssertExpectations(t)
+		mi.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,65 @@
+package hfid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned (wrapped) by HFID when the Limiter of the Options has no token left for the generator
+var ErrRateLimited = errors.New("rate limited")
+
+// Limit A token bucket that holds up to Burst tokens and is refilled at Rate tokens per second. Generating an HFID
+// takes a token. A Rate of zero makes Burst a quota of HFIDs that is never refilled.
+type Limit struct {
+	Rate  float64
+	Burst int64
+}
+
+// Limiter An interface to limit the rate of HFIDs generated by each generator that can be set in Options. Stores can
+// implement it to persist the token buckets so that the limit is shared by all the nodes (e.g. the Redis store).
+type Limiter interface {
+	// Allow takes a token from the bucket of the generator named gName using l. It returns false if there was no token
+	// left.
+	Allow(ctx context.Context, gName string, l Limit) (bool, error)
+}
+
+// MemoryLimiter A Limiter that keeps the token buckets in memory, hence the limit applies to each process separately.
+// It is safe for concurrent use.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Allow takes a token from the in-memory bucket of the generator named gName
+func (ml *MemoryLimiter) Allow(_ context.Context, gName string, l Limit) (bool, error) {
+	now := time.Now()
+	if ml.now != nil {
+		now = ml.now()
+	}
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.buckets == nil {
+		ml.buckets = map[string]bucket{}
+	}
+	b, ok := ml.buckets[gName]
+	if !ok {
+		b = bucket{tokens: float64(l.Burst), updatedAt: now}
+	}
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*l.Rate)
+	b.updatedAt = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	ml.buckets[gName] = b
+	return allowed, nil
+}
@@ -0,0 +1,89 @@
+package hfid
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	ml := &MemoryLimiter{now: func() time.Time { return now }}
+	l := Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		allowed, err := ml.Allow(ctx, "a", l)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, _ := ml.Allow(ctx, "a", l)
+	assert.False(t, allowed, "the burst has been consumed")
+	allowed, _ = ml.Allow(ctx, "b", l)
+	assert.True(t, allowed, "each generator has its own bucket")
+
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		allowed, _ = ml.Allow(ctx, "a", l)
+		assert.True(t, allowed)
+	}
+	allowed, _ = ml.Allow(ctx, "a", l)
+	assert.False(t, allowed, "2 tokens are refilled per second")
+
+	allowed, _ = ml.Allow(ctx, "c", Limit{Burst: 1})
+	assert.True(t, allowed)
+	now = now.Add(time.Hour)
+	allowed, _ = ml.Allow(ctx, "c", Limit{Burst: 1})
+	assert.False(t, allowed, "a quota is never refilled")
+}
+
+// limiterFunc A Limiter that calls the function
+type limiterFunc func(ctx context.Context, gName string, l Limit) (bool, error)
+
+func (f limiterFunc) Allow(ctx context.Context, gName string, l Limit) (bool, error) {
+	return f(ctx, gName, l)
+}
+
+func TestOptions_Limiter(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+	l := Limit{Rate: 1, Burst: 1}
+
+	t.Run("Generates HFIDs while the generator has tokens left", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+		o := Options{Limiter: &MemoryLimiter{}, Limit: l}
+
+		_, err := o.HFID(ctx, g, mgs)
+		assert.NoError(t, err)
+		_, err = o.HFID(ctx, g, mgs)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.EqualError(t, err, "generator 'a' is rate limited")
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Reports Limiter errors", func(t *testing.T) {
+		limiterErr := fmt.Errorf("mock error")
+		mi := NewMockInstrumenter(t)
+		mi.On("StoreError", g.Name, "Allow", limiterErr).Once()
+		mi.On("Generated", g.Name, mock.Anything, 0, limiterErr).Once()
+		o := Options{
+			Limiter: limiterFunc(func(_ context.Context, gName string, got Limit) (bool, error) {
+				assert.Equal(t, g.Name, gName)
+				assert.Equal(t, l, got)
+				return false, limiterErr
+			}),
+			Limit:        l,
+			Instrumenter: mi,
+		}
+
+		_, err := o.HFID(ctx, g, NewMockGeneratorStore(t))
+		assert.ErrorIs(t, err, limiterErr)
+		mi.AssertExpectations(t)
+	})
+}
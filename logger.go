@@ -0,0 +1,37 @@
+package hfid
+
+import "context"
+
+// Logger An interface for structured logging of the generation of HFIDs that can be set in Options. It is a subset of
+// the methods of *slog.Logger, hence a *slog.Logger can be used directly without this package depending on log/slog.
+// args are alternating keys and values as accepted by slog.
+type Logger interface {
+	// DebugContext is used for retries: drawn HFIDs that had been generated before and concurrent updates of a
+	// generator.
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+
+	// InfoContext is used when the Length of a generator is increased.
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+
+	// ErrorContext is used when a call to the store fails or a generator is exhausted (i.e. its Length cannot be
+	// increased without an overflow).
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) DebugContext(context.Context, string, ...interface{}) {}
+
+func (nopLogger) InfoContext(context.Context, string, ...interface{}) {}
+
+func (nopLogger) ErrorContext(context.Context, string, ...interface{}) {}
+
+// logStoreError logs a failed call to the store. op is the name of the GeneratorStore or Claimer method.
+func logStoreError(ctx context.Context, l Logger, g Generator, op string, err error) {
+	l.ErrorContext(ctx, "hfid: store call failed", "generator", g.Name, "op", op, "error", err)
+}
+
+// logExhausted logs a generator whose HFIDs cannot be counted at its Length
+func logExhausted(ctx context.Context, l Logger, g Generator, err error) {
+	l.ErrorContext(ctx, "hfid: generator exhausted", "generator", g.Name, "length", g.Length, "error", err)
+}
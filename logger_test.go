@@ -0,0 +1,139 @@
+package hfid
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// logEntry A logged event recorded by recordingLogger
+type logEntry struct {
+	level string
+	msg   string
+	args  []interface{}
+}
+
+// recordingLogger A Logger that records the logged events
+type recordingLogger struct {
+	entries []logEntry
+}
+
+func (rl *recordingLogger) DebugContext(_ context.Context, msg string, args ...interface{}) {
+	rl.entries = append(rl.entries, logEntry{"DEBUG", msg, args})
+}
+
+func (rl *recordingLogger) InfoContext(_ context.Context, msg string, args ...interface{}) {
+	rl.entries = append(rl.entries, logEntry{"INFO", msg, args})
+}
+
+func (rl *recordingLogger) ErrorContext(_ context.Context, msg string, args ...interface{}) {
+	rl.entries = append(rl.entries, logEntry{"ERROR", msg, args})
+}
+
+func TestOptions_Logger(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewGenerator("a", "", NumericEncoding, 0, 1)
+	assert.NoError(t, err)
+
+	t.Run("Logs retries and growth", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		newG := *g
+		newG.Length++
+		mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(6), nil)
+		mgs.On("Upsert", ctx, newG).Return(nil)
+		mgs.On("Add", ctx, int64(10), g.Name).Return(false, nil).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+
+		rl := &recordingLogger{}
+		_, err := Options{Rand: rand.New(rand.NewSource(1)), Logger: rl}.HFID(ctx, *g, mgs)
+		assert.NoError(t, err)
+		assert.Equal(t, []logEntry{
+			{"INFO", "hfid: increased the length of the generator",
+				[]interface{}{"generator", "a", "previousLength", uint8(1), "length", uint8(2), "fillRatio", 0.6}},
+			{"DEBUG", "hfid: drawn HFID has been generated before, drawing another one",
+				[]interface{}{"generator", "a", "length", uint8(2), "retries", 1}},
+		}, rl.entries)
+	})
+
+	t.Run("Logs concurrent updates of the generator", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		newG := *g
+		newG.Length++
+		conflictErr := ConflictError{Name: g.Name, Reason: "mock conflict"}
+		mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(6), nil).Once()
+		mgs.On("Upsert", ctx, newG).Return(conflictErr).Once()
+		mgs.On("InsertOrGet", ctx, *g).Return(newG, int64(6), nil).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+
+		rl := &recordingLogger{}
+		_, err := Options{Logger: rl}.HFID(ctx, *g, mgs)
+		assert.NoError(t, err)
+		assert.Equal(t, []logEntry{
+			{"DEBUG", "hfid: generator has been updated concurrently, fetching it again",
+				[]interface{}{"generator", "a", "error", conflictErr}},
+		}, rl.entries)
+	})
+
+	t.Run("Logs store failures", func(t *testing.T) {
+		storeErr := fmt.Errorf("mock error")
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, *g).Return(*g, int64(0), nil)
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(false, storeErr)
+
+		rl := &recordingLogger{}
+		_, err := Options{Logger: rl}.HFID(ctx, *g, mgs)
+		assert.Error(t, err)
+		assert.Equal(t, []logEntry{
+			{"ERROR", "hfid: store call failed", []interface{}{"generator", "a", "op", "Add", "error", storeErr}},
+		}, rl.entries)
+	})
+
+	t.Run("Logs exhausted generators", func(t *testing.T) {
+		exhaustedG := Generator{Name: "a", Encoding: NumericEncoding, Length: 19}
+		mcs := NewMockClaimerStore(t)
+
+		rl := &recordingLogger{}
+		_, err := Options{Logger: rl}.HFID(ctx, exhaustedG, mcs)
+		assert.Error(t, err)
+		assert.Len(t, rl.entries, 1)
+		assert.Equal(t, "ERROR", rl.entries[0].level)
+		assert.Equal(t, "hfid: generator exhausted", rl.entries[0].msg)
+	})
+
+	t.Run("Logs claims drawn for a different length or encoding", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		storedG := *g
+		storedG.Length = 2
+		mcs.On("Claim", ctx, *g, mock.Anything).Return(ClaimResult{Generator: storedG}, nil).Once()
+		mcs.On("Claim", ctx, storedG, mock.Anything).Return(ClaimResult{Generator: storedG, Added: true}, nil).Once()
+
+		rl := &recordingLogger{}
+		_, err := Options{Logger: rl}.HFID(ctx, *g, mcs)
+		assert.NoError(t, err)
+		assert.Equal(t, []logEntry{
+			{"DEBUG", "hfid: drawn HFID has been drawn for a different length or encoding, drawing another one",
+				[]interface{}{"generator", "a", "length", uint8(1), "storedLength", uint8(2), "retries", 1}},
+		}, rl.entries)
+	})
+	t.Run("Logs growth of claimed generators", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		newG := *g
+		newG.Length++
+		mcs.On("Claim", ctx, *g, int64(0)).Return(ClaimResult{Generator: newG, Count: 6, Grown: true}, nil).Once()
+		mcs.On("Claim", ctx, newG, mock.Anything).Return(ClaimResult{Generator: newG, Count: 6, Added: true}, nil).Once()
+
+		rl := &recordingLogger{}
+		_, err := Options{Rand: rand.New(rand.NewSource(1)), Logger: rl}.HFID(ctx, *g, mcs)
+		assert.NoError(t, err)
+		assert.Equal(t, []logEntry{
+			{"INFO", "hfid: increased the length of the generator",
+				[]interface{}{"generator", "a", "previousLength", uint8(1), "length", uint8(2), "fillRatio", 0.6}},
+			{"DEBUG", "hfid: drawn HFID has been drawn for a different length or encoding, drawing another one",
+				[]interface{}{"generator", "a", "length", uint8(1), "storedLength", uint8(2), "retries", 1}},
+		}, rl.entries)
+	})
+}
@@ -0,0 +1,38 @@
+package hfid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInstrumenter struct {
+	mock.Mock
+}
+
+func (mi *MockInstrumenter) Generated(gName string, d time.Duration, retries int, err error) {
+	mi.MethodCalled("Generated", gName, d, retries, err)
+}
+
+func (mi *MockInstrumenter) Added(gName string, isNew bool) {
+	mi.MethodCalled("Added", gName, isNew)
+}
+
+func (mi *MockInstrumenter) Grown(gName string, length uint8) {
+	mi.MethodCalled("Grown", gName, length)
+}
+
+func (mi *MockInstrumenter) FillRatio(gName string, ratio float64) {
+	mi.MethodCalled("FillRatio", gName, ratio)
+}
+
+func (mi *MockInstrumenter) StoreError(gName string, op string, err error) {
+	mi.MethodCalled("StoreError", gName, op, err)
+}
+
+func NewMockInstrumenter(t *testing.T) *MockInstrumenter {
+	result := MockInstrumenter{}
+	result.Test(t)
+	return &result
+}
@@ -31,3 +31,33 @@ func NewMockGeneratorStore(t *testing.T) *MockGeneratorStore {
 	result.Test(t)
 	return &result
 }
+
+type MockClaimerStore struct {
+	MockGeneratorStore
+}
+
+func (mcs *MockClaimerStore) Claim(ctx context.Context, g Generator, hfid int64) (ClaimResult, error) {
+	args := mcs.MethodCalled("Claim", ctx, g, hfid)
+	return args.Get(0).(ClaimResult), args.Error(1)
+}
+
+func NewMockClaimerStore(t *testing.T) *MockClaimerStore {
+	result := MockClaimerStore{}
+	result.Test(t)
+	return &result
+}
+
+type MockCounterStore struct {
+	MockGeneratorStore
+}
+
+func (mcs *MockCounterStore) Increment(ctx context.Context, gName string) (int64, error) {
+	args := mcs.MethodCalled("Increment", ctx, gName)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func NewMockCounterStore(t *testing.T) *MockCounterStore {
+	result := MockCounterStore{}
+	result.Test(t)
+	return &result
+}
@@ -0,0 +1,120 @@
+// Package mongo provides a MongoDB implementation for GeneratorStore
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"gitlab.com/alielgamal/hfid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math"
+)
+
+const idKey = "_id"
+const prefixKey = "p"
+const encodingKey = "e"
+const minLengthKey = "m"
+const lengthKey = "l"
+const countKey = "c"
+const generatorKey = "g"
+const nKey = "n"
+
+// GeneratorStore A Struct that wraps two MongoDB collections and implements the GeneratorStore interface provided by
+// HFID. Generators are stored as documents in the Generators collection keyed by the generator's name, while claimed
+// HFIDs are stored as individual documents in the IDs collection that must have a unique compound index on
+// (generator, n). Call EnsureIndexes once to create the index.
+type GeneratorStore struct {
+	Generators *mongo.Collection
+	IDs        *mongo.Collection
+}
+
+type generatorDocument struct {
+	Name      string `bson:"_id"`
+	Prefix    string `bson:"p"`
+	Encoding  string `bson:"e"`
+	MinLength int32  `bson:"m"`
+	Length    int32  `bson:"l"`
+	Count     int64  `bson:"c"`
+}
+
+// EnsureIndexes creates the unique compound index on (generator, n) in the IDs collection which Add relies on to
+// detect duplicate HFIDs. It is safe to call it multiple times.
+func (gs GeneratorStore) EnsureIndexes(ctx context.Context) error {
+	_, err := gs.IDs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: generatorKey, Value: 1}, {Key: nKey, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// InsertOrGet Implemented using a single FindOneAndUpdate command that inserts the generator if it doesn't exist and
+// returns the stored generator along with the count of the HFIDs added to it.
+func (gs GeneratorStore) InsertOrGet(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	r := gs.Generators.FindOneAndUpdate(ctx,
+		bson.D{{Key: idKey, Value: g.Name}},
+		bson.D{{Key: "$setOnInsert", Value: bson.D{
+			{Key: prefixKey, Value: g.Prefix},
+			{Key: encodingKey, Value: string(g.Encoding)},
+			{Key: minLengthKey, Value: int32(g.MinLength)},
+			{Key: lengthKey, Value: int32(g.Length)},
+			{Key: countKey, Value: int64(0)},
+		}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var d generatorDocument
+	if err := r.Decode(&d); err != nil {
+		return g, 0, err
+	}
+
+	g.Prefix = d.Prefix
+	g.Encoding = hfid.Encoding(d.Encoding)
+	if d.MinLength < 0 || d.MinLength > math.MaxUint8 {
+		return g, 0, fmt.Errorf("invalid MinLength value '%d' stored for Generator name '%s'", d.MinLength, g.Name)
+	}
+	g.MinLength = uint8(d.MinLength)
+	if d.Length < 0 || d.Length > math.MaxUint8 {
+		return g, 0, fmt.Errorf("invalid Length value '%d' stored for Generator name '%s'", d.Length, g.Name)
+	}
+	g.Length = uint8(d.Length)
+
+	return g, d.Count, nil
+}
+
+// Upsert Implemented using UpdateOne command with upsert enabled. The count of the HFIDs added to the generator is
+// left untouched.
+func (gs GeneratorStore) Upsert(ctx context.Context, g hfid.Generator) error {
+	_, err := gs.Generators.UpdateOne(ctx,
+		bson.D{{Key: idKey, Value: g.Name}},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: prefixKey, Value: g.Prefix},
+				{Key: encodingKey, Value: string(g.Encoding)},
+				{Key: minLengthKey, Value: int32(g.MinLength)},
+				{Key: lengthKey, Value: int32(g.Length)},
+			}},
+			{Key: "$setOnInsert", Value: bson.D{{Key: countKey, Value: int64(0)}}},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Add Implemented using InsertOne command into the IDs collection. A duplicate key error means the HFID has been added
+// before. Otherwise, the count of the generator is incremented.
+func (gs GeneratorStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	_, err := gs.IDs.InsertOne(ctx, bson.D{{Key: generatorKey, Value: gName}, {Key: nKey, Value: hfid}})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = gs.Generators.UpdateOne(ctx,
+		bson.D{{Key: idKey, Value: gName}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: countKey, Value: int64(1)}}}},
+	)
+	return true, err
+}
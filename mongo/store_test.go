@@ -0,0 +1,214 @@
+package mongo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+var mongoDatabase *mongo.Database
+
+func TestMain(m *testing.M) {
+	uri, set := os.LookupEnv("MONGODB_URI")
+	if !set {
+		uri = "mongodb://localhost:27017"
+	}
+	c, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result := func() int {
+		mongoDatabase = c.Database("test")
+		defer func() { _ = c.Disconnect(context.Background()) }()
+		return m.Run()
+	}()
+
+	os.Exit(result)
+}
+
+func prepareStore(t *testing.T) GeneratorStore {
+	collectionName := strings.ReplaceAll(t.Name(), "/", "_")
+	gs := GeneratorStore{
+		Generators: mongoDatabase.Collection(collectionName),
+		IDs:        mongoDatabase.Collection(collectionName + "_ids"),
+	}
+	_ = gs.Generators.Drop(context.Background())
+	_ = gs.IDs.Drop(context.Background())
+	assert.NoError(t, gs.EnsureIndexes(context.Background()))
+
+	return gs
+}
+
+func TestGeneratorStore_InsertOrGet(t *testing.T) {
+	name := "test"
+	prefix := "t-"
+	encoding := hfid.Encoding(hfid.DefaultEncoding)
+	minLength := uint8(1)
+	length := uint8(2)
+
+	t.Run("new generator is added zero count", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		foundG, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(0), c)
+	})
+
+	t.Run("existing generator is returned", func(t *testing.T) {
+		existingG, err := hfid.NewGenerator(name, "t2-", hfid.NumericEncoding, 2, 3)
+		assert.NoError(t, err)
+
+		gs := prepareStore(t)
+		_, _, err = gs.InsertOrGet(context.Background(), *existingG)
+		assert.NoError(t, err)
+		_, err = gs.Add(context.Background(), 12345, name)
+		assert.NoError(t, err)
+
+		// Attempt to add another generator with the same name
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+
+		// The returned generator should be the existing one not the new one
+		foundG, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *existingG, foundG)
+		assert.Equal(t, int64(1), c)
+	})
+
+	t.Run("fails if the existing generator data is corrupt", func(t *testing.T) {
+		tcs := []struct {
+			name      string
+			minLength int32
+			length    int32
+		}{
+			{"minLength negative", -1, 0},
+			{"minLength overflow", 1000, 0},
+			{"length negative", 0, -1},
+			{"length overflow", 0, 1000},
+		}
+
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				gs := prepareStore(t)
+				_, err := gs.Generators.InsertOne(context.Background(), bson.D{
+					{Key: idKey, Value: name},
+					{Key: prefixKey, Value: prefix},
+					{Key: encodingKey, Value: string(encoding)},
+					{Key: minLengthKey, Value: tc.minLength},
+					{Key: lengthKey, Value: tc.length},
+				})
+				assert.NoError(t, err)
+
+				_, _, err = gs.InsertOrGet(context.Background(), hfid.Generator{Name: name})
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestGeneratorStore_Upsert(t *testing.T) {
+	name := "test"
+	prefix := "t-"
+	encoding := hfid.Encoding(hfid.DefaultEncoding)
+	minLength := uint8(1)
+	length := uint8(2)
+
+	t.Run("creates a new generator if it doesn't exist", func(t *testing.T) {
+		gs := prepareStore(t)
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		err = gs.Upsert(context.Background(), *g)
+		assert.NoError(t, err)
+
+		newG, err := hfid.NewGenerator(name, "t2-", hfid.NumericEncoding, 2, 3)
+		assert.NoError(t, err)
+
+		foundG, c, err := gs.InsertOrGet(context.Background(), *newG)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(0), c)
+	})
+
+	t.Run("updates existing generator", func(t *testing.T) {
+		existingG, err := hfid.NewGenerator(name, "t2-", hfid.NumericEncoding, 2, 3)
+		assert.NoError(t, err)
+
+		gs := prepareStore(t)
+		_, _, err = gs.InsertOrGet(context.Background(), *existingG)
+		assert.NoError(t, err)
+		_, err = gs.Add(context.Background(), 12345, name)
+		assert.NoError(t, err)
+
+		// Upsert
+		g, err := hfid.NewGenerator(name, prefix, encoding, minLength, length)
+		assert.NoError(t, err)
+		err = gs.Upsert(context.Background(), *g)
+		assert.NoError(t, err)
+
+		// The returned generator should be the updated one with the old count
+		foundG, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, *g, foundG)
+		assert.Equal(t, int64(1), c)
+	})
+}
+
+func TestGeneratorStore_Add(t *testing.T) {
+	name := "test"
+	g, err := hfid.NewGenerator(name, "t-", hfid.DefaultEncoding, 1, 2)
+	assert.NoError(t, err)
+
+	addFirstHFID := func(t *testing.T, gs GeneratorStore, id int64) {
+		err := gs.Upsert(context.Background(), *g)
+		assert.NoError(t, err)
+
+		isNew, err := gs.Add(context.Background(), id, name)
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+	}
+
+	t.Run("returns true when the element is unique", func(t *testing.T) {
+		gs := prepareStore(t)
+
+		addFirstHFID(t, gs, 0)
+		isNew, err := gs.Add(context.Background(), 1, name)
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+
+		_, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), c)
+	})
+
+	t.Run("returns false when the element is duplicated", func(t *testing.T) {
+		gs := prepareStore(t)
+
+		addFirstHFID(t, gs, 0)
+		isNew, err := gs.Add(context.Background(), 0, name)
+		assert.NoError(t, err)
+		assert.False(t, isNew)
+
+		_, c, err := gs.InsertOrGet(context.Background(), *g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), c)
+	})
+
+	t.Run("the same HFID can be added to different generators", func(t *testing.T) {
+		gs := prepareStore(t)
+
+		addFirstHFID(t, gs, 0)
+		isNew, err := gs.Add(context.Background(), 0, "other")
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+	})
+}
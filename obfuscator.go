@@ -0,0 +1,116 @@
+package hfid
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Obfuscator Maps numbers (e.g. the integer primary keys of a database) to HFIDs and back, without any store. An HFID
+// is the Prefix of the Generator followed by the key version encoded as a single character of the Encoding, then the
+// number permuted using the key and encoded using at least Length characters. Keys can be rotated by adding a new
+// version to Keys and setting it as the Version: new HFIDs use the new key, while older HFIDs are still decoded using the
+// key of the version they contain.
+type Obfuscator struct {
+	// Generator provides the Prefix and the Encoding of the HFIDs, and the minimum number of characters of the permuted
+	// number as Length.
+	Generator Generator
+
+	// Keys maps the key versions to the keys. A version must be less than the number of characters of the Encoding.
+	Keys map[uint8][]byte
+
+	// Version is the version of the key used to encode numbers
+	Version uint8
+}
+
+// Encode maps n to an HFID using the key of the current Version
+func (o Obfuscator) Encode(n int64) (string, error) {
+	if err := o.Generator.Encoding.Valid(); err != nil {
+		return "", err
+	}
+	if int(o.Version) >= len(o.Generator.Encoding) {
+		return "", fmt.Errorf("key version '%d' must be less than the number of characters of encoding '%s'", o.Version, o.Generator.Encoding)
+	}
+	key, ok := o.Keys[o.Version]
+	if !ok {
+		return "", fmt.Errorf("key version '%d' not found", o.Version)
+	}
+	if n < 0 {
+		return "", fmt.Errorf("cannot encode negative number %d", n)
+	}
+
+	g := Generator{Encoding: o.Generator.Encoding, Length: o.length(n)}
+	max, err := g.maxHFID()
+	if err != nil {
+		return "", fmt.Errorf("%d is too large to be encoded using encoding '%s': %s", n, g.Encoding, err)
+	}
+	permuted, err := Permutation{Key: key}.Permute(n, max+1)
+	if err != nil {
+		return "", err
+	}
+	body, err := g.encodeHFID(permuted)
+	if err != nil {
+		return "", err
+	}
+	return o.Generator.Prefix + string(g.Encoding[o.Version]) + body, nil
+}
+
+// Decode maps an HFID returned by Encode back to its number using the key of the version found in the HFID
+func (o Obfuscator) Decode(hfid string) (int64, error) {
+	e := o.Generator.Encoding
+	if err := e.Valid(); err != nil {
+		return 0, err
+	}
+	if !strings.HasPrefix(hfid, o.Generator.Prefix) || len(hfid) < len(o.Generator.Prefix)+2 {
+		return 0, fmt.Errorf("cannot decode HFID '%s' it must have the prefix '%s' followed by at least 2 characters", hfid, o.Generator.Prefix)
+	}
+	rest := hfid[len(o.Generator.Prefix):]
+	version := strings.IndexByte(string(e), rest[0])
+	if version < 0 {
+		return 0, fmt.Errorf("invalid key version character '%s' encountered while decoding '%s'", string(rest[0]), hfid)
+	}
+	key, ok := o.Keys[uint8(version)]
+	if !ok {
+		return 0, fmt.Errorf("key version '%d' of HFID '%s' not found", version, hfid)
+	}
+	body := rest[1:]
+	if len(body) > 255 {
+		return 0, fmt.Errorf("cannot decode HFID '%s' it is too long", hfid)
+	}
+
+	g := Generator{Encoding: e, Length: uint8(len(body))}
+	max, err := g.maxHFID()
+	if err != nil {
+		return 0, fmt.Errorf("cannot decode HFID '%s' it is too long: %s", hfid, err)
+	}
+	permuted, err := e.Decode(body)
+	if err != nil {
+		return 0, err
+	}
+	n, err := Permutation{Key: key}.Invert(permuted, max+1)
+	if err != nil {
+		return 0, err
+	}
+	// Only the length used by Encode is accepted so that each number has a single HFID per key version
+	if o.length(n) != g.Length {
+		return 0, fmt.Errorf("cannot decode HFID '%s' its length is not the one used to encode %d", hfid, n)
+	}
+	return n, nil
+}
+
+// length returns the number of characters used to encode n, which is the number of characters of n once encoded but
+// not less than the Length of the Generator
+func (o Obfuscator) length(n int64) uint8 {
+	base := int64(len(o.Generator.Encoding))
+	length := uint8(1)
+	for p := base; p <= n; p *= base {
+		length++
+		if p > math.MaxInt64/base {
+			break
+		}
+	}
+	if length < o.Generator.Length {
+		return o.Generator.Length
+	}
+	return length
+}
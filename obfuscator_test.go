@@ -0,0 +1,89 @@
+package hfid
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscator(t *testing.T) {
+	g := Generator{Name: "user", Prefix: "U-", Encoding: DefaultEncoding, Length: 4}
+	o := Obfuscator{Generator: g, Keys: map[uint8][]byte{1: []byte("first secret")}, Version: 1}
+
+	t.Run("Encodes numbers to unique HFIDs and decodes them back", func(t *testing.T) {
+		seen := map[string]bool{}
+		for _, n := range []int64{0, 1, 2, 35, 36, 1000, 1679615, 1679616, 123456789, math.MaxInt64 / 36} {
+			hfid, err := o.Encode(n)
+			assert.NoError(t, err)
+			assert.Regexp(t, "^U-1[0-9A-Z]{4,}$", hfid)
+			assert.False(t, seen[hfid])
+			seen[hfid] = true
+
+			got, err := o.Decode(hfid)
+			assert.NoError(t, err)
+			assert.Equal(t, n, got)
+		}
+	})
+
+	t.Run("Doesn't reveal consecutive numbers", func(t *testing.T) {
+		h1, err := o.Encode(41)
+		assert.NoError(t, err)
+		h2, err := o.Encode(42)
+		assert.NoError(t, err)
+		assert.NotEqual(t, h1[:len(h1)-1], h2[:len(h2)-1])
+	})
+
+	t.Run("Decodes HFIDs encoded with older keys after a rotation", func(t *testing.T) {
+		old, err := o.Encode(42)
+		assert.NoError(t, err)
+
+		rotated := o
+		rotated.Keys = map[uint8][]byte{1: []byte("first secret"), 2: []byte("second secret")}
+		rotated.Version = 2
+		hfid, err := rotated.Encode(42)
+		assert.NoError(t, err)
+		assert.Equal(t, "U-2", hfid[:3])
+		assert.NotEqual(t, old, hfid)
+
+		for _, h := range []string{old, hfid} {
+			n, err := rotated.Decode(h)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(42), n)
+		}
+	})
+
+	t.Run("Fails", func(t *testing.T) {
+		tests := []struct {
+			name string
+			o    Obfuscator
+			hfid string
+		}{
+			{"when the prefix is different", o, "X-10000"},
+			{"when the key version is unknown", o, "U-30000"},
+			{"when the HFID contains characters outside the Encoding", o, "U-1000a"},
+			{"when the HFID is too short", o, "U-1"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := tt.o.Decode(tt.hfid)
+				assert.Error(t, err)
+			})
+		}
+
+		// 42 permuted using 5 characters instead of the 4 characters used by Encode
+		permuted, err := Permutation{Key: o.Keys[1]}.Permute(42, 36*36*36*36*36)
+		assert.NoError(t, err)
+		body, err := Generator{Encoding: DefaultEncoding, Length: 5}.encodeHFID(permuted)
+		assert.NoError(t, err)
+		_, err = o.Decode("U-1" + body)
+		assert.Error(t, err, "only the length used by Encode is accepted")
+
+		_, err = Obfuscator{Generator: g, Keys: o.Keys, Version: 2}.Encode(1)
+		assert.Error(t, err, "the key of the Version must exist")
+		_, err = o.Encode(-1)
+		assert.Error(t, err)
+		_, err = Obfuscator{Generator: Generator{Encoding: NumericEncoding}, Keys: o.Keys, Version: 1}.Encode(math.MaxInt64)
+		assert.Error(t, err, "numbers that cannot be encoded without an overflow are rejected")
+	})
+}
@@ -0,0 +1,153 @@
+// Package otel provides OpenTelemetry tracing for HFID generation using a GeneratorStore decorator, so that any store
+// implementation can be traced.
+package otel
+
+import (
+	"context"
+	"errors"
+	"gitlab.com/alielgamal/hfid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "gitlab.com/alielgamal/hfid/otel"
+
+const generatorNameKey = attribute.Key("hfid.generator.name")
+const generatorLengthKey = attribute.Key("hfid.generator.length")
+const attemptKey = attribute.Key("hfid.attempt")
+const attemptsKey = attribute.Key("hfid.attempts")
+const outcomeKey = attribute.Key("hfid.outcome")
+
+// GeneratorStore A Struct that decorates a GeneratorStore by wrapping each of its calls with a span. Use
+// NewGeneratorStore to create it, so that the Claimer implementation of the decorated store is traced too.
+type GeneratorStore struct {
+	hfid.GeneratorStore
+
+	// Tracer is used to create the spans. The Tracer of the global TracerProvider is used when nil.
+	Tracer trace.Tracer
+}
+
+// ClaimerStore A GeneratorStore decorator for stores that implement Claimer
+type ClaimerStore struct {
+	GeneratorStore
+	Claimer hfid.Claimer
+}
+
+// NewGeneratorStore decorates s with spans created using t. A ClaimerStore is returned if s implements Claimer.
+// Otherwise, a GeneratorStore is returned so that HFID keeps using InsertOrGet, Upsert and Add.
+func NewGeneratorStore(s hfid.GeneratorStore, t trace.Tracer) hfid.GeneratorStore {
+	gs := GeneratorStore{GeneratorStore: s, Tracer: t}
+	if c, ok := s.(hfid.Claimer); ok {
+		return ClaimerStore{GeneratorStore: gs, Claimer: c}
+	}
+	return gs
+}
+
+// HFID calls o.HFID within a span that is the parent of the spans created by a decorated store. The span records the
+// number of attempts made to add an HFID and the outcome.
+func HFID(ctx context.Context, t trace.Tracer, o hfid.Options, g hfid.Generator, s hfid.GeneratorStore) (string, error) {
+	ctx, span := tracer(t).Start(ctx, "hfid.HFID", trace.WithAttributes(generatorNameKey.String(g.Name)))
+	defer span.End()
+
+	attempts := 0
+	result, err := o.HFID(context.WithValue(ctx, attemptsContextKey{}, &attempts), g, s)
+	span.SetAttributes(attemptsKey.Int(attempts))
+	end(span, err, "success")
+	return result, err
+}
+
+// InsertOrGet wraps InsertOrGet of the decorated store with a span that records the stored Length
+func (gs GeneratorStore) InsertOrGet(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	ctx, span := gs.start(ctx, "hfid.InsertOrGet", g.Name)
+	defer span.End()
+
+	storedG, c, err := gs.GeneratorStore.InsertOrGet(ctx, g)
+	if err == nil {
+		span.SetAttributes(generatorLengthKey.Int(int(storedG.Length)))
+	}
+	end(span, err, "success")
+	return storedG, c, err
+}
+
+// Upsert wraps Upsert of the decorated store with a span. A ConflictError is recorded as a "conflict" outcome.
+func (gs GeneratorStore) Upsert(ctx context.Context, g hfid.Generator) error {
+	ctx, span := gs.start(ctx, "hfid.Upsert", g.Name, generatorLengthKey.Int(int(g.Length)))
+	defer span.End()
+
+	err := gs.GeneratorStore.Upsert(ctx, g)
+	var conflictErr hfid.ConflictError
+	if errors.As(err, &conflictErr) {
+		span.SetAttributes(outcomeKey.String("conflict"))
+		return err
+	}
+	end(span, err, "success")
+	return err
+}
+
+// Add wraps Add of the decorated store with a span that records the attempt number and whether the HFID was new
+func (gs GeneratorStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	ctx, span := gs.start(ctx, "hfid.Add", gName, attempt(ctx))
+	defer span.End()
+
+	isNew, err := gs.GeneratorStore.Add(ctx, hfid, gName)
+	end(span, err, outcome(isNew))
+	return isNew, err
+}
+
+// Claim wraps Claim of the decorated store with a span that records the attempt number, the Length the HFID has been
+// drawn for and whether the HFID was claimed
+func (cs ClaimerStore) Claim(ctx context.Context, g hfid.Generator, hfid int64) (hfid.ClaimResult, error) {
+	ctx, span := cs.start(ctx, "hfid.Claim", g.Name, attempt(ctx), generatorLengthKey.Int(int(g.Length)))
+	defer span.End()
+
+	res, err := cs.Claimer.Claim(ctx, g, hfid)
+	end(span, err, outcome(res.Added))
+	return res, err
+}
+
+func (gs GeneratorStore) start(ctx context.Context, name string, gName string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer(gs.Tracer).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, generatorNameKey.String(gName))...))
+}
+
+// attemptsContextKey The key of the attempts counter that HFID stores in the context
+type attemptsContextKey struct{}
+
+// attempt increments the attempts counter found in the context and returns the attempt number as an attribute. The
+// attempt number is 0 when the call isn't made within HFID of this package.
+func attempt(ctx context.Context) attribute.KeyValue {
+	attempts, ok := ctx.Value(attemptsContextKey{}).(*int)
+	if !ok {
+		return attemptKey.Int(0)
+	}
+	*attempts++
+	return attemptKey.Int(*attempts)
+}
+
+func outcome(isNew bool) string {
+	if isNew {
+		return "new"
+	}
+	return "duplicate"
+}
+
+// end records the outcome of the call on the span, or the error if the call failed
+func end(span trace.Span, err error, successOutcome string) {
+	if err != nil {
+		span.SetAttributes(outcomeKey.String("error"))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(outcomeKey.String(successOutcome))
+}
+
+func tracer(t trace.Tracer) trace.Tracer {
+	if t != nil {
+		return t
+	}
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,160 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"math/rand"
+	"testing"
+)
+
+// memoryStore A GeneratorStore that keeps the generators and the added HFIDs in memory
+type memoryStore struct {
+	generators map[string]hfid.Generator
+	hfids      map[int64]bool
+	upsertErr  error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{generators: map[string]hfid.Generator{}, hfids: map[int64]bool{}}
+}
+
+func (ms *memoryStore) InsertOrGet(_ context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	if storedG, ok := ms.generators[g.Name]; ok {
+		return storedG, int64(len(ms.hfids)), nil
+	}
+	ms.generators[g.Name] = g
+	return g, 0, nil
+}
+
+func (ms *memoryStore) Upsert(_ context.Context, g hfid.Generator) error {
+	if ms.upsertErr != nil {
+		return ms.upsertErr
+	}
+	ms.generators[g.Name] = g
+	return nil
+}
+
+func (ms *memoryStore) Add(_ context.Context, hfid int64, _ string) (bool, error) {
+	if ms.hfids[hfid] {
+		return false, nil
+	}
+	ms.hfids[hfid] = true
+	return true, nil
+}
+
+// claimerStore A memoryStore that implements Claimer
+type claimerStore struct {
+	*memoryStore
+}
+
+func (cs claimerStore) Claim(ctx context.Context, g hfid.Generator, id int64) (hfid.ClaimResult, error) {
+	isNew, err := cs.Add(ctx, id, g.Name)
+	return hfid.ClaimResult{Generator: g, Added: isNew}, err
+}
+
+func newTracerProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	sr := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)), sr
+}
+
+func attributes(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	result := map[attribute.Key]attribute.Value{}
+	for _, kv := range s.Attributes() {
+		result[kv.Key] = kv.Value
+	}
+	return result
+}
+
+func TestNewGeneratorStore(t *testing.T) {
+	_, ok := NewGeneratorStore(newMemoryStore(), nil).(hfid.Claimer)
+	assert.False(t, ok, "stores that don't implement Claimer must not be decorated as a Claimer")
+
+	_, ok = NewGeneratorStore(claimerStore{newMemoryStore()}, nil).(hfid.Claimer)
+	assert.True(t, ok)
+}
+
+func TestHFID(t *testing.T) {
+	ctx := context.Background()
+	g := hfid.Generator{Name: "g", Encoding: hfid.NumericEncoding, Length: 1}
+
+	t.Run("Creates spans for HFID and every store call", func(t *testing.T) {
+		tp, sr := newTracerProvider()
+		tracer := tp.Tracer("test")
+		ms := newMemoryStore()
+		// Seed 1 draws 0 first, hence the first attempt is a duplicate
+		ms.hfids[0] = true
+		s := NewGeneratorStore(ms, tracer)
+
+		_, err := HFID(ctx, tracer, hfid.Options{Rand: rand.New(rand.NewSource(1))}, g, s)
+		assert.NoError(t, err)
+
+		spans := sr.Ended()
+		names := make([]string, 0, len(spans))
+		for _, s := range spans {
+			names = append(names, s.Name())
+		}
+		assert.Equal(t, []string{"hfid.InsertOrGet", "hfid.Add", "hfid.Add", "hfid.HFID"}, names)
+
+		root := spans[3]
+		for _, s := range spans[:3] {
+			assert.Equal(t, root.SpanContext().SpanID(), s.Parent().SpanID())
+		}
+		assert.Equal(t, attribute.IntValue(1), attributes(spans[0])[generatorLengthKey])
+		assert.Equal(t, attribute.IntValue(1), attributes(spans[1])[attemptKey])
+		assert.Equal(t, attribute.StringValue("duplicate"), attributes(spans[1])[outcomeKey])
+		assert.Equal(t, attribute.IntValue(2), attributes(spans[2])[attemptKey])
+		assert.Equal(t, attribute.StringValue("new"), attributes(spans[2])[outcomeKey])
+		assert.Equal(t, attribute.IntValue(2), attributes(root)[attemptsKey])
+		assert.Equal(t, attribute.StringValue("success"), attributes(root)[outcomeKey])
+		assert.Equal(t, attribute.StringValue("g"), attributes(root)[generatorNameKey])
+	})
+
+	t.Run("Records conflicts and errors of Upsert", func(t *testing.T) {
+		tp, sr := newTracerProvider()
+		tracer := tp.Tracer("test")
+		ms := newMemoryStore()
+		ms.generators[g.Name] = g
+		// 5 of the 10 HFIDs have been generated, hence the Length is increased
+		for i := int64(0); i < 5; i++ {
+			ms.hfids[i] = true
+		}
+		ms.upsertErr = fmt.Errorf("mock error")
+		s := NewGeneratorStore(ms, tracer)
+
+		_, err := HFID(ctx, tracer, hfid.Options{}, g, s)
+		assert.Error(t, err)
+		spans := sr.Ended()
+		assert.Equal(t, "hfid.Upsert", spans[1].Name())
+		assert.Equal(t, codes.Error, spans[1].Status().Code)
+		assert.Equal(t, attribute.IntValue(2), attributes(spans[1])[generatorLengthKey])
+		assert.Equal(t, codes.Error, spans[2].Status().Code)
+		assert.Equal(t, attribute.StringValue("error"), attributes(spans[2])[outcomeKey])
+
+		sr = tracetest.NewSpanRecorder()
+		tp.RegisterSpanProcessor(sr)
+		ms.upsertErr = hfid.ConflictError{Name: g.Name, Reason: "mock conflict"}
+		err = s.Upsert(ctx, g)
+		assert.Error(t, err)
+		assert.Equal(t, attribute.StringValue("conflict"), attributes(sr.Ended()[0])[outcomeKey])
+		assert.Equal(t, codes.Unset, sr.Ended()[0].Status().Code)
+	})
+
+	t.Run("Creates spans for Claim", func(t *testing.T) {
+		tp, sr := newTracerProvider()
+		tracer := tp.Tracer("test")
+		s := NewGeneratorStore(claimerStore{newMemoryStore()}, tracer)
+
+		_, err := HFID(ctx, tracer, hfid.Options{}, g, s)
+		assert.NoError(t, err)
+		spans := sr.Ended()
+		assert.Equal(t, "hfid.Claim", spans[0].Name())
+		assert.Equal(t, attribute.IntValue(1), attributes(spans[0])[attemptKey])
+		assert.Equal(t, attribute.StringValue("new"), attributes(spans[0])[outcomeKey])
+	})
+}
@@ -0,0 +1,100 @@
+package hfid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// Permutation A keyed format-preserving permutation of [0, n) built using a balanced Feistel network over the smallest
+// even number of bits that can hold n - 1. Cycle walking is used to stay within [0, n), i.e. the network is applied
+// again until the result is less than n. The round function is HMAC-SHA256 keyed with Key. Without the Key, the
+// permuted numbers don't reveal the order of the original numbers.
+type Permutation struct {
+	Key []byte
+
+	// Rounds is the number of rounds of the Feistel network. 8 is used when zero.
+	Rounds int
+}
+
+// Permute maps x in [0, n) to a unique number in [0, n)
+func (p Permutation) Permute(x int64, n int64) (int64, error) {
+	halfBits, err := p.validate(x, n)
+	if err != nil {
+		return 0, err
+	}
+	y := uint64(x)
+	for {
+		y = p.encrypt(y, halfBits)
+		if y < uint64(n) {
+			return int64(y), nil
+		}
+	}
+}
+
+// Invert maps y in [0, n) back to the number that Permute maps to y
+func (p Permutation) Invert(y int64, n int64) (int64, error) {
+	halfBits, err := p.validate(y, n)
+	if err != nil {
+		return 0, err
+	}
+	x := uint64(y)
+	for {
+		x = p.decrypt(x, halfBits)
+		if x < uint64(n) {
+			return int64(x), nil
+		}
+	}
+}
+
+// validate checks the arguments and returns the number of bits of each half of the Feistel network
+func (p Permutation) validate(x int64, n int64) (uint, error) {
+	if len(p.Key) == 0 {
+		return 0, fmt.Errorf("the key of the permutation cannot be empty")
+	}
+	if n <= 0 || x < 0 || x >= n {
+		return 0, fmt.Errorf("%d is not between [0, %d)", x, n)
+	}
+	halfBits := uint(bits.Len64(uint64(n-1))+1) / 2
+	if halfBits == 0 {
+		halfBits = 1
+	}
+	return halfBits, nil
+}
+
+func (p Permutation) encrypt(x uint64, halfBits uint) uint64 {
+	mask := uint64(1)<<halfBits - 1
+	l, r := x>>halfBits, x&mask
+	for i := 0; i < p.rounds(); i++ {
+		l, r = r, l^(p.round(i, r)&mask)
+	}
+	return l<<halfBits | r
+}
+
+func (p Permutation) decrypt(x uint64, halfBits uint) uint64 {
+	mask := uint64(1)<<halfBits - 1
+	l, r := x>>halfBits, x&mask
+	for i := p.rounds() - 1; i >= 0; i-- {
+		l, r = r^(p.round(i, l)&mask), l
+	}
+	return l<<halfBits | r
+}
+
+// round is the round function of the Feistel network
+func (p Permutation) round(i int, r uint64) uint64 {
+	var msg [9]byte
+	msg[0] = byte(i)
+	binary.BigEndian.PutUint64(msg[1:], r)
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write(msg[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func (p Permutation) rounds() int {
+	if p.Rounds > 0 {
+		return p.Rounds
+	}
+	return 8
+}
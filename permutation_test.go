@@ -0,0 +1,57 @@
+package hfid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermutation(t *testing.T) {
+	p := Permutation{Key: []byte("secret")}
+
+	for _, n := range []int64{1, 2, 10, 36, 1000, 1296} {
+		seen := make(map[int64]bool, n)
+		for x := int64(0); x < n; x++ {
+			y, err := p.Permute(x, n)
+			assert.NoError(t, err)
+			assert.True(t, y >= 0 && y < n, "%d is not between [0, %d)", y, n)
+			assert.False(t, seen[y], "%d has been permuted to %d twice", x, y)
+			seen[y] = true
+
+			got, err := p.Invert(y, n)
+			assert.NoError(t, err)
+			assert.Equal(t, x, got)
+		}
+	}
+
+	t.Run("Permutes large numbers", func(t *testing.T) {
+		n := int64(1<<62 + 12345)
+		y, err := p.Permute(n-1, n)
+		assert.NoError(t, err)
+		got, err := p.Invert(y, n)
+		assert.NoError(t, err)
+		assert.Equal(t, n-1, got)
+	})
+
+	t.Run("Depends on the key", func(t *testing.T) {
+		var same int
+		other := Permutation{Key: []byte("other secret")}
+		for x := int64(0); x < 1000; x++ {
+			y1, _ := p.Permute(x, 1000)
+			y2, _ := other.Permute(x, 1000)
+			if y1 == y2 {
+				same++
+			}
+		}
+		assert.Less(t, same, 20)
+	})
+
+	t.Run("Fails with invalid arguments", func(t *testing.T) {
+		_, err := Permutation{}.Permute(0, 10)
+		assert.Error(t, err)
+		_, err = p.Permute(10, 10)
+		assert.Error(t, err)
+		_, err = p.Invert(-1, 10)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,113 @@
+// Package prometheus provides a Prometheus implementation for the Instrumenter interface provided by HFID
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Instrumenter A Struct that implements the Instrumenter interface provided by HFID by updating Prometheus metrics
+// labeled by the generator name. Use NewInstrumenter to create and register the metrics.
+type Instrumenter struct {
+	duration    *prometheus.HistogramVec
+	retries     *prometheus.HistogramVec
+	adds        *prometheus.CounterVec
+	growths     *prometheus.CounterVec
+	length      *prometheus.GaugeVec
+	fillRatio   *prometheus.GaugeVec
+	storeErrors *prometheus.CounterVec
+}
+
+// NewInstrumenter creates the metrics prefixed with namespace (e.g. "hfid") and registers them with reg. The following
+// metrics are created:
+//   - generation_duration_seconds: histogram of the duration of HFID calls labeled by generator and result
+//   - generation_retries: histogram of the number of retries per HFID call labeled by generator
+//   - adds_total: counter of the drawn HFIDs labeled by generator and outcome (new or duplicate)
+//   - length_growths_total: counter of the increases of the Length labeled by generator
+//   - length: gauge of the last Length the generator has grown to labeled by generator
+//   - fill_ratio: gauge of the estimated ratio of the generated HFIDs to all the HFIDs at the current Length labeled by
+//     generator
+//   - store_errors_total: counter of the failed store calls labeled by generator and operation
+func NewInstrumenter(reg prometheus.Registerer, namespace string) (*Instrumenter, error) {
+	in := &Instrumenter{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "generation_duration_seconds",
+			Help:      "Duration of generating an HFID.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"generator", "result"}),
+		retries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "generation_retries",
+			Help:      "Number of drawn HFIDs that had been generated before per generated HFID.",
+			Buckets:   []float64{0, 1, 2, 3, 5, 8, 13, 21},
+		}, []string{"generator"}),
+		adds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "adds_total",
+			Help:      "Number of drawn HFIDs added to the store by outcome.",
+		}, []string{"generator", "outcome"}),
+		growths: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "length_growths_total",
+			Help:      "Number of times the Length of a generator has been increased.",
+		}, []string{"generator"}),
+		length: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "length",
+			Help:      "Last Length a generator has been increased to.",
+		}, []string{"generator"}),
+		fillRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "fill_ratio",
+			Help:      "Estimated ratio of the generated HFIDs to all the HFIDs that can be generated at the current Length.",
+		}, []string{"generator"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_errors_total",
+			Help:      "Number of failed store calls by operation.",
+		}, []string{"generator", "operation"}),
+	}
+
+	for _, c := range []prometheus.Collector{in.duration, in.retries, in.adds, in.growths, in.length, in.fillRatio, in.storeErrors} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// Generated observes the duration and the retries of an HFID call
+func (in *Instrumenter) Generated(gName string, d time.Duration, retries int, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	in.duration.WithLabelValues(gName, result).Observe(d.Seconds())
+	in.retries.WithLabelValues(gName).Observe(float64(retries))
+}
+
+// Added counts the outcome of adding a drawn HFID
+func (in *Instrumenter) Added(gName string, isNew bool) {
+	outcome := "new"
+	if !isNew {
+		outcome = "duplicate"
+	}
+	in.adds.WithLabelValues(gName, outcome).Inc()
+}
+
+// Grown counts the increase of the Length and records the new Length
+func (in *Instrumenter) Grown(gName string, length uint8) {
+	in.growths.WithLabelValues(gName).Inc()
+	in.length.WithLabelValues(gName).Set(float64(length))
+}
+
+// FillRatio records the estimated fill ratio
+func (in *Instrumenter) FillRatio(gName string, ratio float64) {
+	in.fillRatio.WithLabelValues(gName).Set(ratio)
+}
+
+// StoreError counts the failed store call
+func (in *Instrumenter) StoreError(gName string, op string, _ error) {
+	in.storeErrors.WithLabelValues(gName, op).Inc()
+}
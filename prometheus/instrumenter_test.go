@@ -0,0 +1,82 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstrumenter(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	in, err := NewInstrumenter(reg, "hfid")
+	assert.NoError(t, err)
+
+	var _ hfid.Instrumenter = in
+	in.Generated("g", 2*time.Millisecond, 3, nil)
+	in.Generated("g", time.Millisecond, 0, fmt.Errorf("mock error"))
+	in.Added("g", true)
+	in.Added("g", false)
+	in.Added("g", false)
+	in.Grown("g", 3)
+	in.FillRatio("g", 0.25)
+	in.StoreError("g", "Add", fmt.Errorf("mock error"))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP hfid_adds_total Number of drawn HFIDs added to the store by outcome.
+# TYPE hfid_adds_total counter
+hfid_adds_total{generator="g",outcome="duplicate"} 2
+hfid_adds_total{generator="g",outcome="new"} 1
+# HELP hfid_fill_ratio Estimated ratio of the generated HFIDs to all the HFIDs that can be generated at the current Length.
+# TYPE hfid_fill_ratio gauge
+hfid_fill_ratio{generator="g"} 0.25
+# HELP hfid_length Last Length a generator has been increased to.
+# TYPE hfid_length gauge
+hfid_length{generator="g"} 3
+# HELP hfid_length_growths_total Number of times the Length of a generator has been increased.
+# TYPE hfid_length_growths_total counter
+hfid_length_growths_total{generator="g"} 1
+# HELP hfid_store_errors_total Number of failed store calls by operation.
+# TYPE hfid_store_errors_total counter
+hfid_store_errors_total{generator="g",operation="Add"} 1
+`), "hfid_adds_total", "hfid_fill_ratio", "hfid_length", "hfid_length_growths_total", "hfid_store_errors_total"))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(in.duration))
+	assert.Equal(t, 1, testutil.CollectAndCount(in.retries))
+
+	t.Run("Fails if the metrics are already registered", func(t *testing.T) {
+		_, err := NewInstrumenter(reg, "hfid")
+		assert.Error(t, err)
+	})
+
+	t.Run("Observes HFID generation using Options", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		in, err := NewInstrumenter(reg, "hfid")
+		assert.NoError(t, err)
+
+		g := hfid.Generator{Name: "g", Encoding: hfid.NumericEncoding, Length: 2}
+		_, err = hfid.Options{Instrumenter: in}.HFID(context.Background(), g, failingStore{})
+		assert.Error(t, err)
+		assert.Equal(t, float64(1), testutil.ToFloat64(in.storeErrors.WithLabelValues("g", "InsertOrGet")))
+	})
+}
+
+// failingStore A GeneratorStore that fails every call
+type failingStore struct{}
+
+func (failingStore) InsertOrGet(context.Context, hfid.Generator) (hfid.Generator, int64, error) {
+	return hfid.Generator{}, 0, fmt.Errorf("mock error")
+}
+
+func (failingStore) Upsert(context.Context, hfid.Generator) error {
+	return fmt.Errorf("mock error")
+}
+
+func (failingStore) Add(context.Context, int64, string) (bool, error) {
+	return false, fmt.Errorf("mock error")
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"gitlab.com/alielgamal/hfid"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Get Implemented using HMGet command followed by PFCount command if the generator exists
+func (gs GeneratorStore) Get(ctx context.Context, gName string) (hfid.Generator, int64, bool, error) {
+	g := hfid.Generator{Name: gName}
+	getCmd := gs.HMGet(ctx, gs.generatorKey(gName), prefixKey, encodingKey, minLengthKey, lengthKey)
+	if getCmd.Err() != nil {
+		return g, 0, false, getCmd.Err()
+	}
+	if getCmd.Val()[0] == nil {
+		return g, 0, false, nil
+	}
+	g, err := parseGenerator(g, getCmd.Val())
+	if err != nil {
+		return g, 0, true, err
+	}
+
+	countCmd := gs.PFCount(ctx, gs.hllKey(gName))
+	if countCmd.Err() != nil {
+		return g, 0, true, countCmd.Err()
+	}
+	return g, countCmd.Val(), true, nil
+}
+
+// List Implemented using SCAN command to find the Hash keys that start with KeyPrefix (on every master node when
+// running against a Redis Cluster) and keeping the ones that have a Length field. Without a KeyPrefix, every such Hash
+// in the database is considered a generator.
+func (gs GeneratorStore) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	var mu sync.Mutex
+	scan := func(ctx context.Context, c redis.UniversalClient) error {
+		iter := c.ScanType(ctx, 0, escapeGlob(gs.KeyPrefix)+"*", 0, "hash").Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	var err error
+	if cc, ok := gs.UniversalClient.(*redis.ClusterClient); ok {
+		err = cc.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error { return scan(ctx, c) })
+	} else {
+		err = scan(ctx, gs.UniversalClient)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, gs.KeyPrefix)
+		if gs.HashTag {
+			if !strings.HasPrefix(name, "{") || !strings.HasSuffix(name, "}") {
+				continue
+			}
+			name = name[1 : len(name)-1]
+		}
+		existsCmd := gs.HExists(ctx, key, lengthKey)
+		if existsCmd.Err() != nil {
+			return nil, existsCmd.Err()
+		}
+		if existsCmd.Val() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete Implemented using DEL command for the generator's Hash, its HyperLogLog, the structure of its Membership, its
+// token bucket and its counter. The keys are deleted one by one since they may belong to different slots when running
+// against a Redis Cluster.
+func (gs GeneratorStore) Delete(ctx context.Context, gName string) error {
+	keys := []string{gs.generatorKey(gName), gs.hllKey(gName), gs.limitKey(gName), gs.counterKey(gName)}
+	if m := gs.membership(gName); m.Type != HyperLogLogMembership {
+		keys = append(keys, gs.membershipKey(gName, m))
+	}
+	for _, key := range keys {
+		if err := gs.Del(ctx, key).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGlob escapes the characters that have a special meaning in the patterns of SCAN command
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
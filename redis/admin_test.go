@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"testing"
+)
+
+func TestGeneratorStore_Get(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	gs := GeneratorStore{UniversalClient: uc}
+
+	t.Run("Doesn't create a generator that doesn't exist", func(t *testing.T) {
+		_, _, found, err := gs.Get(ctx, t.Name())
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.False(t, mr.Exists(t.Name()))
+	})
+
+	t.Run("Returns an existing generator and its count", func(t *testing.T) {
+		g := hfid.Generator{Name: t.Name(), Prefix: "G-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2}
+		assert.NoError(t, gs.Upsert(ctx, g))
+		_, err := gs.Add(ctx, 1, g.Name)
+		assert.NoError(t, err)
+
+		foundG, c, found, err := gs.Get(ctx, g.Name)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, g, foundG)
+		assert.Equal(t, int64(1), c)
+	})
+}
+
+func TestGeneratorStore_List(t *testing.T) {
+	ctx := context.Background()
+	g := hfid.Generator{Prefix: "G-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2}
+
+	tests := []struct {
+		name string
+		gs   GeneratorStore
+	}{
+		{"lists the generators without a key prefix", GeneratorStore{}},
+		{"lists the generators with a key prefix", GeneratorStore{KeyPrefix: "hfid*:"}},
+		{"lists the generators with a hash tag", GeneratorStore{KeyPrefix: "hfid:", HashTag: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			tt.gs.UniversalClient = redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+			for _, name := range []string{"b", "a"} {
+				g.Name = name
+				assert.NoError(t, tt.gs.Upsert(ctx, g))
+				_, err := tt.gs.Add(ctx, 1, name)
+				assert.NoError(t, err)
+			}
+			// Unrelated keys are skipped
+			mr.HSet(tt.gs.KeyPrefix+"other", "field", "value")
+			mr.HSet("unrelated", lengthKey, "1")
+
+			names, err := tt.gs.List(ctx)
+			assert.NoError(t, err)
+			if tt.gs.KeyPrefix == "" {
+				assert.Equal(t, []string{"a", "b", "unrelated"}, names)
+			} else {
+				assert.Equal(t, []string{"a", "b"}, names)
+			}
+		})
+	}
+}
+
+func TestGeneratorStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	gs := GeneratorStore{UniversalClient: uc, Memberships: map[string]Membership{"g": {Type: SetMembership}}}
+
+	g := hfid.Generator{Name: "g", Prefix: "G-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2}
+	assert.NoError(t, gs.Upsert(ctx, g))
+	_, err := gs.Add(ctx, 1, g.Name)
+	assert.NoError(t, err)
+	_, err = gs.Allow(ctx, g.Name, hfid.Limit{Rate: 1, Burst: 1})
+	assert.NoError(t, err)
+	_, err = gs.Increment(ctx, g.Name)
+	assert.NoError(t, err)
+
+	assert.NoError(t, gs.Delete(ctx, g.Name))
+	assert.Empty(t, mr.Keys())
+
+	_, _, found, err := gs.Get(ctx, g.Name)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,15 @@
+package redis
+
+import (
+	"context"
+)
+
+func (gs GeneratorStore) counterKey(gName string) string {
+	return gs.generatorKey(gName) + "-counter"
+}
+
+// Increment Implemented using INCR command on a String with the generator's key followed by -counter
+func (gs GeneratorStore) Increment(ctx context.Context, gName string) (int64, error) {
+	incrCmd := gs.Incr(ctx, gs.counterKey(gName))
+	return incrCmd.Val() - 1, incrCmd.Err()
+}
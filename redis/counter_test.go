@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"testing"
+)
+
+func TestGeneratorStore_Increment(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	gs := GeneratorStore{UniversalClient: uc, KeyPrefix: "hfid:"}
+
+	for i := int64(0); i < 3; i++ {
+		c, err := gs.Increment(ctx, "g")
+		assert.NoError(t, err)
+		assert.Equal(t, i, c)
+	}
+	assert.True(t, mr.Exists("hfid:g-counter"))
+
+	t.Run("Generates unique HFIDs using a CounterSource", func(t *testing.T) {
+		g := hfid.Generator{Name: "counted", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 1}
+		cs := hfid.CounterSource{Store: gs, Permutation: hfid.Permutation{Key: []byte("secret")}}
+		seen := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			id, err := cs.Generate(ctx, g)
+			assert.NoError(t, err)
+			assert.False(t, seen[id], "%s has been generated twice", id)
+			seen[id] = true
+		}
+		storedG, _, err := gs.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(3), storedG.Length)
+	})
+}
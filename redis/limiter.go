@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"gitlab.com/alielgamal/hfid"
+	"strconv"
+)
+
+const tokensKey = "t"
+const updatedAtKey = "u"
+
+func (gs GeneratorStore) limitKey(gName string) string {
+	return gs.generatorKey(gName) + "-limit"
+}
+
+// allowScript refills the token bucket stored in a Hash using the time of the Redis server, then takes a token if there
+// is one left. The Hash expires once the bucket would be full again. It returns 1 if a token has been taken, 0
+// otherwise.
+var allowScript = redis.NewScript(`
+local now = redis.call('TIME')
+local t = tonumber(now[1]) + tonumber(now[2]) / 1000000
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local b = redis.call('HMGET', KEYS[1], '` + tokensKey + `', '` + updatedAtKey + `')
+local tokens = tonumber(b[1])
+local updatedAt = tonumber(b[2])
+if tokens == nil or updatedAt == nil then
+	tokens = burst
+	updatedAt = t
+end
+tokens = math.min(burst, tokens + math.max(0, t - updatedAt) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], '` + tokensKey + `', tostring(tokens), '` + updatedAtKey + `', tostring(t))
+if rate > 0 then
+	redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+end
+return allowed
+`)
+
+// Allow Implemented using a single EVALSHA command that runs a Lua script refilling the generator's token bucket then
+// taking a token. The bucket is stored in a Hash with the generator's key followed by -limit, hence it is shared by all
+// the nodes using the same Redis. The time of the Redis server is used to refill the bucket.
+func (gs GeneratorStore) Allow(ctx context.Context, gName string, l hfid.Limit) (bool, error) {
+	res, err := allowScript.Run(ctx, gs, []string{gs.limitKey(gName)},
+		strconv.FormatFloat(l.Rate, 'f', -1, 64), l.Burst).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
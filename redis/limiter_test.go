@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/alielgamal/hfid"
+	"testing"
+	"time"
+)
+
+func TestGeneratorStore_Allow(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	now := time.Now()
+	mr.SetTime(now)
+	uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	gs := GeneratorStore{UniversalClient: uc, KeyPrefix: "hfid:"}
+	l := hfid.Limit{Rate: 0.5, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		allowed, err := gs.Allow(ctx, "g", l)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, err := gs.Allow(ctx, "g", l)
+	assert.NoError(t, err)
+	assert.False(t, allowed, "the burst has been consumed")
+	assert.True(t, mr.Exists("hfid:g-limit"))
+
+	mr.SetTime(now.Add(2 * time.Second))
+	allowed, err = gs.Allow(ctx, "g", l)
+	assert.NoError(t, err)
+	assert.True(t, allowed, "a token is refilled every 2 seconds")
+	allowed, err = gs.Allow(ctx, "g", l)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	t.Run("Limits HFID", func(t *testing.T) {
+		g := hfid.Generator{Name: "limited", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 3}
+		o := hfid.Options{Limiter: gs, Limit: hfid.Limit{Burst: 1}}
+		_, err := o.HFID(ctx, g, gs)
+		assert.NoError(t, err)
+		_, err = o.HFID(ctx, g, gs)
+		assert.ErrorIs(t, err, hfid.ErrRateLimited)
+	})
+}
@@ -0,0 +1,62 @@
+package redis
+
+// MembershipType The structure used by GeneratorStore to track the HFIDs added to a generator
+type MembershipType string
+
+// HyperLogLogMembership tracks the HFIDs using the generator's HyperLogLog only. It is the cheapest option, but the
+// HyperLogLog may report a new HFID as added before (which only causes an extra retry) or, rarely, an existing HFID as
+// new.
+const HyperLogLogMembership MembershipType = "hll"
+
+// BloomMembership tracks the HFIDs using a RedisBloom filter (requires the RedisBloom module). A Bloom filter never
+// reports an existing HFID as new, but may report a new HFID as added before with a probability of its error rate.
+const BloomMembership MembershipType = "bloom"
+
+// SetMembership tracks the HFIDs using a Set that holds every HFID exactly. It is the most accurate option but uses the
+// most memory.
+const SetMembership MembershipType = "set"
+
+// Membership Specifies how the HFIDs added to a generator are tracked. Regardless of the type, the generator's
+// HyperLogLog is always updated since it is used to estimate the number of HFIDs generated.
+type Membership struct {
+	Type MembershipType
+
+	// ErrorRate of the Bloom filter. The RedisBloom default is used when zero.
+	ErrorRate float64
+
+	// Capacity of the Bloom filter. The RedisBloom default is used when zero.
+	Capacity int64
+}
+
+func (gs GeneratorStore) membership(gName string) Membership {
+	m := gs.Memberships[gName]
+	if m.Type == "" {
+		m.Type = HyperLogLogMembership
+	}
+	return m
+}
+
+// membershipKey returns the key of the structure tracking the HFIDs of the generator
+func (gs GeneratorStore) membershipKey(gName string, m Membership) string {
+	switch m.Type {
+	case BloomMembership:
+		return gs.generatorKey(gName) + "-bf"
+	case SetMembership:
+		return gs.generatorKey(gName) + "-set"
+	default:
+		return gs.hllKey(gName)
+	}
+}
+
+// bloomInsertArgs returns the BF.INSERT command arguments that create the Bloom filter with the configured parameters if
+// it doesn't exist and add hfid to it
+func (m Membership) bloomInsertArgs(key string, hfid int64) []interface{} {
+	args := []interface{}{"BF.INSERT", key}
+	if m.Capacity > 0 {
+		args = append(args, "CAPACITY", m.Capacity)
+	}
+	if m.ErrorRate > 0 {
+		args = append(args, "ERROR", m.ErrorRate)
+	}
+	return append(args, "ITEMS", hfid)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"gitlab.com/alielgamal/hfid"
+	"math"
 	"strconv"
 )
 
@@ -16,55 +17,241 @@ const lengthKey = "l"
 
 // GeneratorStore A Struct that wraps a Redis UniversalClient and implements the GeneratorStore interface provided by
 // HFID. This implementation utilizes a Hash stored with the generator's key and a HyperLogLog stored with the
-// generator's name-hll
+// generator's key followed by -hll. The generator's key is its name prefixed with KeyPrefix and wrapped with {} when
+// HashTag is enabled. Generators can track their HFIDs exactly using a Set or a Bloom filter instead of the HyperLogLog
+// by configuring their Membership.
 type GeneratorStore struct {
 	redis.UniversalClient
-}
 
-func hllKey(gName string) string {
-	return gName + "-hll"
+	// KeyPrefix is prepended to all the keys used by the store to avoid collisions with other data in the same database
+	KeyPrefix string
+
+	// HashTag wraps the generator's name in the keys with {} so that all the keys of a generator are stored in the same
+	// Redis Cluster slot. Claim is only atomic against a Redis Cluster when it is enabled, otherwise Claim falls back to
+	// InsertOrGet, Upsert and Add since the keys of a generator may be stored in different slots.
+	HashTag bool
+
+	// Memberships maps generator names to the structure used to track their HFIDs. HyperLogLogMembership is used for
+	// generators that are not found.
+	Memberships map[string]Membership
 }
 
-// InsertOrGet Implemented by HMGet command then followed by either HMSet or PFCount command.
-func (gs GeneratorStore) InsertOrGet(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
-	getCmd := gs.HMGet(ctx, g.Name, prefixKey, encodingKey, minLengthKey, lengthKey)
-	if getCmd.Err() != nil {
-		return g, 0, getCmd.Err()
+func (gs GeneratorStore) generatorKey(gName string) string {
+	if gs.HashTag {
+		return gs.KeyPrefix + "{" + gName + "}"
 	}
+	return gs.KeyPrefix + gName
+}
+
+func (gs GeneratorStore) hllKey(gName string) string {
+	return gs.generatorKey(gName) + "-hll"
+}
 
-	if getCmd.Val()[0] == nil {
-		// Insert the Generator
-		err := gs.Upsert(ctx, g)
+// InsertOrGet Implemented using Get followed by Upsert if the generator doesn't exist.
+func (gs GeneratorStore) InsertOrGet(ctx context.Context, g hfid.Generator) (hfid.Generator, int64, error) {
+	storedG, c, found, err := gs.Get(ctx, g.Name)
+	if err != nil {
 		return g, 0, err
 	}
-	g.Prefix = getCmd.Val()[0].(string)
-	g.Encoding = hfid.Encoding(getCmd.Val()[1].(string))
-	ml, err := strconv.ParseInt(getCmd.Val()[2].(string), 10, 8)
+	if found {
+		return storedG, c, nil
+	}
+
+	// Insert the Generator
+	err = gs.Upsert(ctx, g)
+	return g, 0, err
+}
+
+// parseGenerator sets the properties of g from the values of the prefix, encoding, minLength & length hash fields in the
+// same order
+func parseGenerator(g hfid.Generator, vals []interface{}) (hfid.Generator, error) {
+	g.Prefix = vals[0].(string)
+	g.Encoding = hfid.Encoding(vals[1].(string))
+	ml, err := strconv.ParseInt(vals[2].(string), 10, 8)
 	if err != nil || ml < 0 {
-		return g, 0, fmt.Errorf("invalid MinLength value '%s' stored for Generator name '%s'", getCmd.Val()[2].(string), g.Name)
+		return g, fmt.Errorf("invalid MinLength value '%s' stored for Generator name '%s'", vals[2].(string), g.Name)
 	}
 	g.MinLength = uint8(ml)
-	l, err := strconv.ParseInt(getCmd.Val()[3].(string), 10, 8)
+	l, err := strconv.ParseInt(vals[3].(string), 10, 8)
 	if err != nil || l < 0 {
-		return g, 0, fmt.Errorf("invalid Length value '%s' stored for Generator name '%s'", getCmd.Val()[3].(string), g.Name)
+		return g, fmt.Errorf("invalid Length value '%s' stored for Generator name '%s'", vals[3].(string), g.Name)
 	}
 	g.Length = uint8(l)
-
-	countCmd := gs.PFCount(ctx, hllKey(g.Name))
-	if countCmd.Err() != nil {
-		return g, 0, countCmd.Err()
-	}
-	return g, countCmd.Val(), nil
+	return g, nil
 }
 
 // Upsert Implemented using HMSet command
 func (gs GeneratorStore) Upsert(ctx context.Context, g hfid.Generator) error {
-	setCmd := gs.HMSet(ctx, g.Name, prefixKey, g.Prefix, encodingKey, string(g.Encoding), minLengthKey, g.MinLength, lengthKey, g.Length)
+	setCmd := gs.HMSet(ctx, gs.generatorKey(g.Name), prefixKey, g.Prefix, encodingKey, string(g.Encoding), minLengthKey, g.MinLength, lengthKey, g.Length)
 	return setCmd.Err()
 }
 
-// Add Implemented using PFAdd command
+// Add Implemented using PFAdd command. If the generator's Membership is a Set or a Bloom filter, SAdd or BF.INSERT
+// command is pipelined with PFAdd command and its result is returned instead.
 func (gs GeneratorStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
-	addCmd := gs.PFAdd(ctx, hllKey(gName), hfid)
-	return addCmd.Val() == 1, addCmd.Err()
+	m := gs.membership(gName)
+	switch m.Type {
+	case SetMembership:
+		var addCmd *redis.IntCmd
+		_, err := gs.Pipelined(ctx, func(p redis.Pipeliner) error {
+			addCmd = p.SAdd(ctx, gs.membershipKey(gName, m), hfid)
+			p.PFAdd(ctx, gs.hllKey(gName), hfid)
+			return nil
+		})
+		return addCmd.Val() == 1, err
+	case BloomMembership:
+		var addCmd *redis.Cmd
+		_, err := gs.Pipelined(ctx, func(p redis.Pipeliner) error {
+			addCmd = p.Do(ctx, m.bloomInsertArgs(gs.membershipKey(gName, m), hfid)...)
+			p.PFAdd(ctx, gs.hllKey(gName), hfid)
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+		r, ok := addCmd.Val().([]interface{})
+		if !ok || len(r) != 1 {
+			return false, fmt.Errorf("unexpected BF.INSERT result %v", addCmd.Val())
+		}
+		return r[0] == int64(1), nil
+	default:
+		addCmd := gs.PFAdd(ctx, gs.hllKey(gName), hfid)
+		return addCmd.Val() == 1, addCmd.Err()
+	}
+}
+
+// claimScript loads or creates the generator hash, increases its length if 50% of the HFIDs at the current length have
+// been generated, then adds the candidate to the generator's membership structure only if it was drawn for the
+// resulting length and the stored encoding. It returns the prefix, encoding, minLength & length of the generator followed by 1 if the candidate
+// was added (0 otherwise), the estimate number of HFIDs generated before the claim and 1 if the length was increased (0
+// otherwise).
+var claimScript = redis.NewScript(`
+local g = redis.call('HMGET', KEYS[1], '` + prefixKey + `', '` + encodingKey + `', '` + minLengthKey + `', '` + lengthKey + `')
+if not g[1] then
+	g = {ARGV[1], ARGV[2], ARGV[3], ARGV[4]}
+	redis.call('HMSET', KEYS[1], '` + prefixKey + `', g[1], '` + encodingKey + `', g[2], '` + minLengthKey + `', g[3], '` + lengthKey + `', g[4])
+end
+local l = tonumber(g[4])
+if l == nil then
+	return redis.error_reply('invalid Length value stored for Generator name ' .. KEYS[1])
+end
+local c = redis.call('PFCOUNT', KEYS[2])
+local grown = 0
+if c + 1 > math.floor(math.pow(string.len(g[2]), l) * 0.5) then
+	l = l + 1
+	g[4] = tostring(l)
+	redis.call('HSET', KEYS[1], '` + lengthKey + `', g[4])
+	grown = 1
+end
+local added = 0
+if l == tonumber(ARGV[4]) and g[2] == ARGV[2] then
+	if ARGV[6] == '` + string(SetMembership) + `' then
+		added = redis.call('SADD', KEYS[3], ARGV[5])
+	elseif ARGV[6] == '` + string(BloomMembership) + `' then
+		local args = {'BF.INSERT', KEYS[3]}
+		if tonumber(ARGV[7]) > 0 then
+			table.insert(args, 'CAPACITY')
+			table.insert(args, ARGV[7])
+		end
+		if tonumber(ARGV[8]) > 0 then
+			table.insert(args, 'ERROR')
+			table.insert(args, ARGV[8])
+		end
+		table.insert(args, 'ITEMS')
+		table.insert(args, ARGV[5])
+		added = redis.call(unpack(args))[1]
+	else
+		added = redis.call('PFADD', KEYS[2], ARGV[5])
+	end
+	if added == 1 and KEYS[2] ~= KEYS[3] then
+		redis.call('PFADD', KEYS[2], ARGV[5])
+	end
+end
+return {g[1], g[2], g[3], g[4], added, c, grown}
+`)
+
+// Claim Implemented using a single EVALSHA command that runs a Lua script performing the whole HFID cycle atomically.
+// When running against a Redis Cluster without HashTag, the script cannot access all the keys of the generator, hence
+// the cycle is performed using InsertOrGet, Upsert and Add instead.
+func (gs GeneratorStore) Claim(ctx context.Context, g hfid.Generator, id int64) (hfid.ClaimResult, error) {
+	if _, ok := gs.UniversalClient.(*redis.ClusterClient); ok && !gs.HashTag {
+		return gs.claimUsingCommands(ctx, g, id)
+	}
+
+	m := gs.membership(g.Name)
+	res, err := claimScript.Run(ctx, gs, []string{gs.generatorKey(g.Name), gs.hllKey(g.Name), gs.membershipKey(g.Name, m)},
+		g.Prefix, string(g.Encoding), g.MinLength, g.Length, id, string(m.Type), m.Capacity, m.ErrorRate).Result()
+	if err != nil {
+		return hfid.ClaimResult{Generator: g}, err
+	}
+	r, ok := res.([]interface{})
+	if !ok || len(r) != 7 {
+		return hfid.ClaimResult{Generator: g}, fmt.Errorf("unexpected claim script result %v", res)
+	}
+
+	g, err = parseGenerator(g, r)
+	if err != nil {
+		return hfid.ClaimResult{Generator: g}, err
+	}
+	return hfid.ClaimResult{Generator: g, Count: r[5].(int64), Grown: r[6].(int64) == 1, Added: r[4].(int64) == 1}, nil
+}
+
+// claimUsingCommands performs the same cycle as claimScript using separate commands
+func (gs GeneratorStore) claimUsingCommands(ctx context.Context, g hfid.Generator, id int64) (hfid.ClaimResult, error) {
+	storedG, c, err := gs.InsertOrGet(ctx, g)
+	if err != nil {
+		return hfid.ClaimResult{Generator: g}, err
+	}
+	res := hfid.ClaimResult{Generator: storedG, Count: c}
+	if float64(c+1) > math.Floor(math.Pow(float64(len(storedG.Encoding)), float64(storedG.Length))*0.5) {
+		res.Generator.Length++
+		if err := gs.Upsert(ctx, res.Generator); err != nil {
+			return hfid.ClaimResult{Generator: storedG, Count: c}, err
+		}
+		res.Grown = true
+	}
+	if res.Generator.Length == g.Length && res.Generator.Encoding == g.Encoding {
+		res.Added, err = gs.Add(ctx, id, g.Name)
+	}
+	return res, err
+}
+
+// MigrateKeys renames the keys of the generators named gNames, including the keys of their counters and rate limits,
+// from the key layout used by the from GeneratorStore to the key layout used by this GeneratorStore (e.g. to start using
+// KeyPrefix or HashTag for existing generators). Generators
+// that don't exist in the old layout are skipped, while an error is returned if a generator already exists in the new
+// layout. RENAMENX command is used, hence the old and the new keys must be in the same slot when running against a
+// Redis Cluster. Otherwise, migrate the keys before switching to a Redis Cluster.
+func (gs GeneratorStore) MigrateKeys(ctx context.Context, from GeneratorStore, gNames ...string) error {
+	for _, gName := range gNames {
+		keys := [][2]string{
+			{from.generatorKey(gName), gs.generatorKey(gName)},
+			{from.hllKey(gName), gs.hllKey(gName)},
+			{from.counterKey(gName), gs.counterKey(gName)},
+			{from.limitKey(gName), gs.limitKey(gName)},
+		}
+		if m := gs.membership(gName); m.Type != HyperLogLogMembership {
+			keys = append(keys, [2]string{from.membershipKey(gName, m), gs.membershipKey(gName, m)})
+		}
+		for _, k := range keys {
+			if k[0] == k[1] {
+				continue
+			}
+			existsCmd := gs.Exists(ctx, k[0])
+			if existsCmd.Err() != nil {
+				return existsCmd.Err()
+			}
+			if existsCmd.Val() == 0 {
+				continue
+			}
+			renameCmd := gs.RenameNX(ctx, k[0], k[1])
+			if renameCmd.Err() != nil {
+				return renameCmd.Err()
+			}
+			if !renameCmd.Val() {
+				return fmt.Errorf("cannot migrate key '%s' of Generator name '%s' because key '%s' already exists", k[0], gName, k[1])
+			}
+		}
+	}
+	return nil
 }
@@ -14,7 +14,7 @@ func TestClient_InsertOrGet(t *testing.T) {
 	insertOrGetWithFixtures := func(g hfid.Generator, fixturesF func(*miniredis.Miniredis)) (hfid.Generator, int64, error) {
 		mr := miniredis.RunT(t)
 		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
-		gs := GeneratorStore{uc}
+		gs := GeneratorStore{UniversalClient: uc}
 
 		fixturesF(mr)
 
@@ -53,7 +53,7 @@ func TestClient_InsertOrGet(t *testing.T) {
 				minLengthKey, strconv.FormatUint(uint64(g.MinLength), 10),
 				lengthKey, strconv.FormatUint(uint64(g.Length), 10),
 			)
-			_, err := mr.PfAdd(hllKey(g.Name), "1", "2", "3")
+			_, err := mr.PfAdd(g.Name+"-hll", "1", "2", "3")
 			assert.NoError(t, err)
 		})
 
@@ -95,7 +95,7 @@ func TestClient_InsertOrGet(t *testing.T) {
 
 	t.Run("Fails if Redis client fails", func(t *testing.T) {
 		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{""}})
-		gs := GeneratorStore{uc}
+		gs := GeneratorStore{UniversalClient: uc}
 
 		_, _, err := gs.InsertOrGet(context.Background(), hfid.Generator{})
 		assert.Error(t, err)
@@ -114,7 +114,7 @@ func TestGeneratorStore_Upsert(t *testing.T) {
 	assertUpsertWithFixtures := func(fixturesF func(*miniredis.Miniredis)) {
 		mr := miniredis.RunT(t)
 		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
-		gs := GeneratorStore{uc}
+		gs := GeneratorStore{UniversalClient: uc}
 
 		fixturesF(mr)
 
@@ -138,7 +138,7 @@ func TestGeneratorStore_Upsert(t *testing.T) {
 
 	t.Run("Fails if Redis client fails", func(t *testing.T) {
 		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{""}})
-		gs := GeneratorStore{uc}
+		gs := GeneratorStore{UniversalClient: uc}
 
 		err := gs.Upsert(context.Background(), hfid.Generator{})
 		assert.Error(t, err)
@@ -152,14 +152,14 @@ func TestGeneratorStore_Add(t *testing.T) {
 	assertAddWithFixtures := func(t *testing.T, fixtureF func(*miniredis.Miniredis), expectedReturn bool, expectedCount int) {
 		mr := miniredis.RunT(t)
 		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
-		gs := GeneratorStore{uc}
+		gs := GeneratorStore{UniversalClient: uc}
 
 		fixtureF(mr)
 
 		u, err := gs.Add(context.Background(), id, gName)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedReturn, u)
-		c, err := mr.PfCount(hllKey(gName))
+		c, err := mr.PfCount(gName + "-hll")
 		assert.NoError(t, err)
 		assert.Equal(t, expectedCount, c)
 	}
@@ -170,23 +170,359 @@ func TestGeneratorStore_Add(t *testing.T) {
 
 	t.Run("Updates existing HLL", func(t *testing.T) {
 		assertAddWithFixtures(t, func(mr *miniredis.Miniredis) {
-			_, err := mr.PfAdd(hllKey(gName), "ali")
+			_, err := mr.PfAdd(gName+"-hll", "ali")
 			assert.NoError(t, err)
 		}, true, 2)
 	})
 
 	t.Run("Returns false when the element was previously added to HLL", func(t *testing.T) {
 		assertAddWithFixtures(t, func(mr *miniredis.Miniredis) {
-			_, err := mr.PfAdd(hllKey(gName), strconv.FormatInt(id, 10))
+			_, err := mr.PfAdd(gName+"-hll", strconv.FormatInt(id, 10))
 			assert.NoError(t, err)
 		}, false, 1)
 	})
 
 	t.Run("Fails if Redis client fails", func(t *testing.T) {
 		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{""}})
-		gs := GeneratorStore{uc}
+		gs := GeneratorStore{UniversalClient: uc}
 
 		_, err := gs.Add(context.Background(), id, gName)
 		assert.Error(t, err)
 	})
 }
+
+func TestGeneratorStore_Claim(t *testing.T) {
+	g := hfid.Generator{
+		Name:      "generator",
+		Prefix:    "g-",
+		Encoding:  hfid.NumericEncoding,
+		MinLength: 1,
+		Length:    1,
+	}
+
+	claimWithFixtures := func(t *testing.T, g hfid.Generator, id int64, fixturesF func(*miniredis.Miniredis)) (*miniredis.Miniredis, hfid.ClaimResult, error) {
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		gs := GeneratorStore{UniversalClient: uc}
+
+		fixturesF(mr)
+
+		res, err := gs.Claim(context.Background(), g, id)
+		return mr, res, err
+	}
+
+	t.Run("Inserts a new generator and claims the HFID", func(t *testing.T) {
+		mr, res, err := claimWithFixtures(t, g, 3, func(_ *miniredis.Miniredis) {})
+		assert.NoError(t, err)
+		assert.Equal(t, hfid.ClaimResult{Generator: g, Added: true}, res)
+		assert.Equal(t, g.Prefix, mr.HGet(g.Name, prefixKey))
+		assert.Equal(t, "1", mr.HGet(g.Name, lengthKey))
+		c, err := mr.PfCount(g.Name + "-hll")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, c)
+	})
+
+	t.Run("Returns the existing generator without claiming when the HFID was drawn for another length", func(t *testing.T) {
+		mr, res, err := claimWithFixtures(t, g, 3, func(mr *miniredis.Miniredis) {
+			mr.HSet(g.Name, prefixKey, "e-", encodingKey, hfid.NumericEncoding, minLengthKey, "1", lengthKey, "3")
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, hfid.ClaimResult{
+			Generator: hfid.Generator{Name: g.Name, Prefix: "e-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 3},
+		}, res)
+		c, err := mr.PfCount(g.Name + "-hll")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, c)
+	})
+
+	t.Run("Returns the existing generator without claiming when the HFID was drawn for another encoding", func(t *testing.T) {
+		mr, res, err := claimWithFixtures(t, g, 3, func(mr *miniredis.Miniredis) {
+			mr.HSet(g.Name, prefixKey, g.Prefix, encodingKey, hfid.DefaultEncoding, minLengthKey, "1", lengthKey, "1")
+		})
+		assert.NoError(t, err)
+		assert.False(t, res.Added)
+		assert.Equal(t, hfid.Encoding(hfid.DefaultEncoding), res.Generator.Encoding)
+		c, err := mr.PfCount(g.Name + "-hll")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, c)
+	})
+
+	t.Run("Extends the length when 50% of HFIDs at the current length have been generated", func(t *testing.T) {
+		mr, res, err := claimWithFixtures(t, g, 3, func(mr *miniredis.Miniredis) {
+			mr.HSet(g.Name, prefixKey, g.Prefix, encodingKey, hfid.NumericEncoding, minLengthKey, "1", lengthKey, "1")
+			_, err := mr.PfAdd(g.Name+"-hll", "0", "1", "2", "4", "5")
+			assert.NoError(t, err)
+		})
+		assert.NoError(t, err)
+		assert.False(t, res.Added)
+		assert.True(t, res.Grown)
+		assert.Equal(t, int64(5), res.Count)
+		assert.Equal(t, uint8(2), res.Generator.Length)
+		assert.Equal(t, "2", mr.HGet(g.Name, lengthKey))
+	})
+
+	t.Run("Fails if the existing generator data is corrupt", func(t *testing.T) {
+		_, _, err := claimWithFixtures(t, g, 3, func(mr *miniredis.Miniredis) {
+			mr.HSet(g.Name, prefixKey, g.Prefix, encodingKey, hfid.NumericEncoding, minLengthKey, "a", lengthKey, "1")
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Generates HFIDs through HFID using a single script call", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		gs := GeneratorStore{UniversalClient: uc}
+
+		var id string
+		for i := 0; i < 20; i++ {
+			newID, err := hfid.HFID(context.Background(), g, gs)
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, len(newID), len(id))
+			id = newID
+		}
+		l, err := strconv.Atoi(mr.HGet(g.Name, lengthKey))
+		assert.NoError(t, err)
+		assert.Greater(t, l, 1)
+		assert.Equal(t, len(g.Prefix)+l, len(id))
+	})
+
+	t.Run("Claims HFIDs using separate commands against a Redis Cluster without HashTag", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		cc := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+		gs := GeneratorStore{UniversalClient: cc, Memberships: map[string]Membership{g.Name: {Type: SetMembership}}}
+
+		res, err := gs.Claim(context.Background(), g, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, hfid.ClaimResult{Generator: g, Added: true}, res)
+		res, err = gs.Claim(context.Background(), g, 3)
+		assert.NoError(t, err)
+		assert.False(t, res.Added)
+
+		for _, id := range []int64{0, 1, 2, 4} {
+			_, err = gs.Claim(context.Background(), g, id)
+			assert.NoError(t, err)
+		}
+		res, err = gs.Claim(context.Background(), g, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, hfid.ClaimResult{Generator: hfid.Generator{Name: g.Name, Prefix: g.Prefix, Encoding: g.Encoding,
+			MinLength: 1, Length: 2}, Count: 5, Grown: true}, res)
+		assert.Equal(t, "2", mr.HGet(g.Name, lengthKey))
+		members, err := mr.Members(g.Name + "-set")
+		assert.NoError(t, err)
+		assert.Len(t, members, 5)
+
+		loaded, err := cc.ScriptExists(context.Background(), claimScript.Hash()).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, []bool{false}, loaded, "the claim script must not be used")
+	})
+
+	t.Run("Fails if Redis client fails", func(t *testing.T) {
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{""}})
+		gs := GeneratorStore{UniversalClient: uc}
+
+		_, err := gs.Claim(context.Background(), g, 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestGeneratorStore_Keys(t *testing.T) {
+	tcs := []struct {
+		name                string
+		gs                  GeneratorStore
+		expectedGenerator   string
+		expectedHyperLogLog string
+	}{
+		{"uses the generator name by default", GeneratorStore{}, "g", "g-hll"},
+		{"prepends the key prefix", GeneratorStore{KeyPrefix: "hfid:"}, "hfid:g", "hfid:g-hll"},
+		{"wraps the generator name with a hash tag", GeneratorStore{HashTag: true}, "{g}", "{g}-hll"},
+		{"prepends the key prefix before the hash tag", GeneratorStore{KeyPrefix: "hfid:", HashTag: true}, "hfid:{g}", "hfid:{g}-hll"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedGenerator, tc.gs.generatorKey("g"))
+			assert.Equal(t, tc.expectedHyperLogLog, tc.gs.hllKey("g"))
+		})
+	}
+
+	t.Run("stores generators using the configured keys", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		gs := GeneratorStore{UniversalClient: uc, KeyPrefix: "hfid:", HashTag: true}
+		g := hfid.Generator{Name: "g", Prefix: "g-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 1}
+
+		_, err := hfid.HFID(context.Background(), g, gs)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"hfid:{g}", "hfid:{g}-hll"}, mr.Keys())
+	})
+}
+
+func TestGeneratorStore_MigrateKeys(t *testing.T) {
+	g := hfid.Generator{Name: "g", Prefix: "g-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 2}
+
+	migrateWithFixtures := func(t *testing.T, fixturesF func(*miniredis.Miniredis)) (*miniredis.Miniredis, GeneratorStore, error) {
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		legacy := GeneratorStore{UniversalClient: uc}
+		gs := GeneratorStore{UniversalClient: uc, KeyPrefix: "hfid:", HashTag: true}
+
+		fixturesF(mr)
+
+		return mr, gs, gs.MigrateKeys(context.Background(), legacy, g.Name, "missing")
+	}
+
+	t.Run("Moves the keys of existing generators to the new layout", func(t *testing.T) {
+		mr, gs, err := migrateWithFixtures(t, func(mr *miniredis.Miniredis) {
+			mr.HSet(g.Name, prefixKey, g.Prefix, encodingKey, hfid.NumericEncoding, minLengthKey, "1", lengthKey, "2")
+			_, err := mr.PfAdd(g.Name+"-hll", "1", "2")
+			assert.NoError(t, err)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"hfid:{g}", "hfid:{g}-hll"}, mr.Keys())
+
+		g2, c, err := gs.InsertOrGet(context.Background(), hfid.Generator{Name: g.Name})
+		assert.NoError(t, err)
+		assert.Equal(t, g, g2)
+		assert.Equal(t, int64(2), c)
+	})
+
+	t.Run("Keeps generating unique HFIDs using a CounterSource after the migration", func(t *testing.T) {
+		ctx := context.Background()
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		legacy := GeneratorStore{UniversalClient: uc}
+		gs := GeneratorStore{UniversalClient: uc, KeyPrefix: "hfid:", HashTag: true}
+		p := hfid.Permutation{Key: []byte("secret")}
+
+		seen := map[string]bool{}
+		generate := func(s GeneratorStore, n int) {
+			for i := 0; i < n; i++ {
+				id, err := hfid.CounterSource{Store: s, Permutation: p}.Generate(ctx, g)
+				assert.NoError(t, err)
+				assert.False(t, seen[id], "%s has been generated twice", id)
+				seen[id] = true
+			}
+		}
+		generate(legacy, 30)
+		assert.NoError(t, gs.MigrateKeys(ctx, legacy, g.Name))
+		assert.Equal(t, []string{"hfid:{g}", "hfid:{g}-counter"}, mr.Keys())
+		generate(gs, 30)
+	})
+
+	t.Run("Keeps the rate limit of existing generators after the migration", func(t *testing.T) {
+		ctx := context.Background()
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		legacy := GeneratorStore{UniversalClient: uc}
+		gs := GeneratorStore{UniversalClient: uc, KeyPrefix: "hfid:", HashTag: true}
+		l := hfid.Limit{Rate: 0.001, Burst: 1}
+
+		allowed, err := legacy.Allow(ctx, g.Name, l)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		assert.NoError(t, gs.MigrateKeys(ctx, legacy, g.Name))
+		assert.Equal(t, []string{"hfid:{g}-limit"}, mr.Keys())
+		allowed, err = gs.Allow(ctx, g.Name, l)
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("Fails if the generator already exists in the new layout", func(t *testing.T) {
+		_, _, err := migrateWithFixtures(t, func(mr *miniredis.Miniredis) {
+			mr.HSet(g.Name, prefixKey, g.Prefix)
+			mr.HSet("hfid:{g}", prefixKey, g.Prefix)
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Fails if Redis client fails", func(t *testing.T) {
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{""}})
+		gs := GeneratorStore{UniversalClient: uc, HashTag: true}
+
+		err := gs.MigrateKeys(context.Background(), GeneratorStore{UniversalClient: uc}, g.Name)
+		assert.Error(t, err)
+	})
+}
+
+func TestGeneratorStore_Memberships(t *testing.T) {
+	gName := "generator"
+	id := int64(1)
+
+	newStore := func(t *testing.T, m Membership) (*miniredis.Miniredis, GeneratorStore) {
+		mr := miniredis.RunT(t)
+		uc := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+		return mr, GeneratorStore{UniversalClient: uc, Memberships: map[string]Membership{gName: m}}
+	}
+
+	t.Run("Set membership tracks HFIDs exactly and updates the HLL", func(t *testing.T) {
+		mr, gs := newStore(t, Membership{Type: SetMembership})
+
+		isNew, err := gs.Add(context.Background(), id, gName)
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+		isNew, err = gs.Add(context.Background(), id, gName)
+		assert.NoError(t, err)
+		assert.False(t, isNew)
+
+		members, err := mr.Members(gName + "-set")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1"}, members)
+		c, err := mr.PfCount(gName + "-hll")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, c)
+	})
+
+	t.Run("Set membership is used by Claim", func(t *testing.T) {
+		mr, gs := newStore(t, Membership{Type: SetMembership})
+		g := hfid.Generator{Name: gName, Prefix: "g-", Encoding: hfid.NumericEncoding, MinLength: 1, Length: 1}
+
+		res, err := gs.Claim(context.Background(), g, id)
+		assert.NoError(t, err)
+		assert.True(t, res.Added)
+		res, err = gs.Claim(context.Background(), g, id)
+		assert.NoError(t, err)
+		assert.False(t, res.Added)
+
+		members, err := mr.Members(gName + "-set")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1"}, members)
+		c, err := mr.PfCount(gName + "-hll")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, c)
+	})
+
+	t.Run("Bloom membership uses BF.INSERT", func(t *testing.T) {
+		// miniredis doesn't support RedisBloom, hence only the failure is asserted here
+		_, gs := newStore(t, Membership{Type: BloomMembership, ErrorRate: 0.001, Capacity: 1000})
+
+		_, err := gs.Add(context.Background(), id, gName)
+		assert.ErrorContains(t, err, "BF.INSERT")
+	})
+
+	t.Run("Bloom filter is created with the configured parameters", func(t *testing.T) {
+		tcs := []struct {
+			name     string
+			m        Membership
+			expected []interface{}
+		}{
+			{"defaults", Membership{Type: BloomMembership}, []interface{}{"BF.INSERT", "k", "ITEMS", id}},
+			{"capacity", Membership{Type: BloomMembership, Capacity: 10}, []interface{}{"BF.INSERT", "k", "CAPACITY", int64(10), "ITEMS", id}},
+			{"error rate", Membership{Type: BloomMembership, ErrorRate: 0.1}, []interface{}{"BF.INSERT", "k", "ERROR", 0.1, "ITEMS", id}},
+		}
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				assert.Equal(t, tc.expected, tc.m.bloomInsertArgs("k", id))
+			})
+		}
+	})
+
+	t.Run("Membership keys are migrated", func(t *testing.T) {
+		mr, legacy := newStore(t, Membership{Type: SetMembership})
+		gs := legacy
+		gs.KeyPrefix = "hfid:"
+		_, err := legacy.Add(context.Background(), id, gName)
+		assert.NoError(t, err)
+
+		assert.NoError(t, gs.MigrateKeys(context.Background(), legacy, gName))
+		assert.Equal(t, []string{"hfid:generator-hll", "hfid:generator-set"}, mr.Keys())
+	})
+}
@@ -0,0 +1,116 @@
+package hfid
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryingStore A GeneratorStore decorator that retries the calls that fail with exponential backoff and full jitter.
+// Use NewRetryingStore to create it, so that the Claimer implementation of the decorated store is retried too.
+//
+// Retrying Add (or Claim) is safe: a call that failed may still have added the HFID (e.g. it timed out after the store
+// added it), in which case the retry reports the HFID as generated before and HFID draws a fresh one. Hence an HFID is
+// never returned twice, at the cost of a few HFIDs that are added but never returned.
+type RetryingStore struct {
+	GeneratorStore
+
+	// MaxAttempts is the maximum number of calls made for each call to the RetryingStore
+	MaxAttempts int
+
+	// BaseDelay is the maximum delay before the first retry. It is doubled after every retry up to MaxDelay.
+	BaseDelay time.Duration
+
+	// MaxDelay is the maximum delay before any retry
+	MaxDelay time.Duration
+}
+
+// RetryingClaimerStore A RetryingStore for stores that implement Claimer
+type RetryingClaimerStore struct {
+	RetryingStore
+	Claimer Claimer
+}
+
+// NewRetryingStore decorates s so that each call is made up to maxAttempts times, waiting a random delay of up to
+// baseDelay * 2^retry (capped to maxDelay) between the calls. A RetryingClaimerStore is returned if s implements
+// Claimer. Otherwise, a RetryingStore is returned so that HFID keeps using InsertOrGet, Upsert and Add.
+func NewRetryingStore(s GeneratorStore, maxAttempts int, baseDelay time.Duration, maxDelay time.Duration) GeneratorStore {
+	rs := RetryingStore{GeneratorStore: s, MaxAttempts: maxAttempts, BaseDelay: baseDelay, MaxDelay: maxDelay}
+	if c, ok := s.(Claimer); ok {
+		return RetryingClaimerStore{RetryingStore: rs, Claimer: c}
+	}
+	return rs
+}
+
+// InsertOrGet retries InsertOrGet of the decorated store
+func (rs RetryingStore) InsertOrGet(ctx context.Context, g Generator) (Generator, int64, error) {
+	var storedG Generator
+	var c int64
+	err := rs.retry(ctx, func() error {
+		var err error
+		storedG, c, err = rs.GeneratorStore.InsertOrGet(ctx, g)
+		return err
+	})
+	return storedG, c, err
+}
+
+// Upsert retries Upsert of the decorated store. A ConflictError is not retried since HFID recovers from it by fetching
+// the generator again.
+func (rs RetryingStore) Upsert(ctx context.Context, g Generator) error {
+	return rs.retry(ctx, func() error {
+		return rs.GeneratorStore.Upsert(ctx, g)
+	})
+}
+
+// Add retries Add of the decorated store with the same hfid. If a retry reports hfid as generated before, it may have
+// been added by the call that failed, hence false is returned so that HFID draws a fresh HFID.
+func (rs RetryingStore) Add(ctx context.Context, hfid int64, gName string) (bool, error) {
+	var isNew bool
+	err := rs.retry(ctx, func() error {
+		var err error
+		isNew, err = rs.GeneratorStore.Add(ctx, hfid, gName)
+		return err
+	})
+	return isNew, err
+}
+
+// Claim retries Claim of the decorated store with the same hfid. Like Add, a retry that doesn't claim hfid makes HFID
+// draw a fresh HFID.
+func (rcs RetryingClaimerStore) Claim(ctx context.Context, g Generator, hfid int64) (ClaimResult, error) {
+	var res ClaimResult
+	err := rcs.retry(ctx, func() error {
+		var err error
+		res, err = rcs.Claimer.Claim(ctx, g, hfid)
+		return err
+	})
+	return res, err
+}
+
+// retry calls f until it succeeds, returns a ConflictError, ctx is done or MaxAttempts calls have been made
+func (rs RetryingStore) retry(ctx context.Context, f func() error) error {
+	delay := rs.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := f()
+		var conflictErr ConflictError
+		if err == nil || errors.As(err, &conflictErr) || ctx.Err() != nil || attempt >= rs.MaxAttempts {
+			return err
+		}
+
+		if rs.MaxDelay > 0 && delay > rs.MaxDelay {
+			delay = rs.MaxDelay
+		}
+		var jitter time.Duration
+		if delay > 0 {
+			jitter = time.Duration(rand.Int63n(int64(delay) + 1))
+		}
+		t := time.NewTimer(jitter)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
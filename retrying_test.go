@@ -0,0 +1,91 @@
+package hfid
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewRetryingStore(t *testing.T) {
+	_, ok := NewRetryingStore(NewMockGeneratorStore(t), 3, 0, 0).(Claimer)
+	assert.False(t, ok, "stores that don't implement Claimer must not be decorated as a Claimer")
+
+	_, ok = NewRetryingStore(NewMockClaimerStore(t), 3, 0, 0).(Claimer)
+	assert.True(t, ok)
+}
+
+func TestRetryingStore(t *testing.T) {
+	ctx := context.Background()
+	g := Generator{Name: "a", Encoding: NumericEncoding, MinLength: 1, Length: 2}
+	storeErr := fmt.Errorf("mock error")
+
+	t.Run("Retries until the call succeeds", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(0), storeErr).Twice()
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(5), nil).Once()
+		s := NewRetryingStore(mgs, 3, time.Millisecond, time.Millisecond)
+
+		_, c, err := s.InsertOrGet(ctx, g)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), c)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Returns the error after MaxAttempts calls", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("Upsert", ctx, g).Return(storeErr).Times(3)
+		s := NewRetryingStore(mgs, 3, time.Millisecond, time.Millisecond)
+
+		assert.ErrorIs(t, s.Upsert(ctx, g), storeErr)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Doesn't retry a ConflictError", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("Upsert", ctx, g).Return(ConflictError{Name: g.Name, Reason: "mock conflict"}).Once()
+		s := NewRetryingStore(mgs, 3, time.Millisecond, time.Millisecond)
+
+		assert.ErrorAs(t, s.Upsert(ctx, g), &ConflictError{})
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Stops retrying when the context is done", func(t *testing.T) {
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("Add", cancelledCtx, int64(7), g.Name).Run(func(mock.Arguments) { cancel() }).Return(false, storeErr).Once()
+		s := NewRetryingStore(mgs, 3, time.Hour, time.Hour)
+
+		_, err := s.Add(cancelledCtx, 7, g.Name)
+		assert.ErrorIs(t, err, storeErr)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Draws a fresh HFID when a retried Add reports a duplicate", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		mgs.On("InsertOrGet", ctx, g).Return(g, int64(0), nil)
+		// The first call timed out after adding the HFID
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(false, storeErr).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(false, nil).Once()
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil).Once()
+		s := NewRetryingStore(mgs, 3, 0, 0)
+
+		_, err := HFID(ctx, g, s)
+		assert.NoError(t, err)
+		mgs.AssertExpectations(t)
+	})
+
+	t.Run("Retries Claim", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		mcs.On("Claim", ctx, g, int64(7)).Return(ClaimResult{Generator: g}, storeErr).Once()
+		mcs.On("Claim", ctx, g, int64(7)).Return(ClaimResult{Generator: g, Added: true}, nil).Once()
+		s := NewRetryingStore(mcs, 3, 0, 0).(Claimer)
+
+		res, err := s.Claim(ctx, g, 7)
+		assert.NoError(t, err)
+		assert.True(t, res.Added)
+	})
+}
@@ -0,0 +1,134 @@
+package hfid
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Alphabets of the placeholders of templates
+const (
+	letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits  = "0123456789"
+)
+
+// templatePart A literal or a placeholder of a template. Placeholders have an alphabet, while literals don't.
+type templatePart struct {
+	literal  byte
+	alphabet string
+}
+
+// parseTemplate parses the template of a generator using Encoding e for X placeholders. See Generator.Template for the
+// syntax.
+func parseTemplate(template string, e Encoding) ([]templatePart, error) {
+	var parts []templatePart
+	for i := 0; i < len(template); i++ {
+		switch c := template[i]; c {
+		case 'A':
+			parts = append(parts, templatePart{alphabet: letters})
+		case '9':
+			parts = append(parts, templatePart{alphabet: digits})
+		case 'X':
+			if err := e.Valid(); err != nil {
+				return nil, fmt.Errorf("invalid Encoding ('%s') used by template '%s': %s", e, template, err)
+			}
+			parts = append(parts, templatePart{alphabet: string(e)})
+		case '[':
+			end := strings.IndexByte(template[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '[' at %d in template '%s'", i, template)
+			}
+			alphabet := template[i+1 : i+1+end]
+			if err := Encoding(alphabet).Valid(); err != nil {
+				return nil, fmt.Errorf("invalid alphabet '%s' in template '%s': %s", alphabet, template, err)
+			}
+			parts = append(parts, templatePart{alphabet: alphabet})
+			i += end + 1
+		case '\\':
+			if i+1 == len(template) {
+				return nil, fmt.Errorf("template '%s' cannot end with '\\'", template)
+			}
+			i++
+			parts = append(parts, templatePart{literal: template[i]})
+		default:
+			parts = append(parts, templatePart{literal: c})
+		}
+	}
+	return parts, nil
+}
+
+// placeholders returns the placeholders of the parts in the same order
+func placeholders(parts []templatePart) []templatePart {
+	var result []templatePart
+	for _, p := range parts {
+		if p.alphabet != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// countTemplateHFIDs returns the number of HFIDs that can be encoded using the last length placeholders, which is the
+// product of the sizes of their alphabets
+func countTemplateHFIDs(parts []templatePart, length uint8) (int64, error) {
+	ps := placeholders(parts)
+	if int(length) > len(ps) {
+		return 0, fmt.Errorf("length %d is greater than the %d placeholders of the template", length, len(ps))
+	}
+	count := int64(1)
+	for _, p := range ps[len(ps)-int(length):] {
+		if count > math.MaxInt64/int64(len(p.alphabet)) {
+			return 0, fmt.Errorf("the last %d placeholders of the template would result an overflow", length)
+		}
+		count *= int64(len(p.alphabet))
+	}
+	return count, nil
+}
+
+// encodeTemplate encodes n as a mixed radix number where each placeholder is a digit in the base of the size of its
+// alphabet, the last placeholder being the least significant. Literals are copied as they are.
+func encodeTemplate(parts []templatePart, n int64) string {
+	result := make([]byte, len(parts))
+	for i := len(parts) - 1; i >= 0; i-- {
+		p := parts[i]
+		if p.alphabet == "" {
+			result[i] = p.literal
+			continue
+		}
+		base := int64(len(p.alphabet))
+		result[i] = p.alphabet[n%base]
+		n /= base
+	}
+	return string(result)
+}
+
+// decodeTemplate decodes s back into the mixed radix number it encodes. An error is returned if s doesn't match the
+// template or any placeholder before the last length placeholders isn't the first character of its alphabet.
+func decodeTemplate(parts []templatePart, length uint8, s string) (int64, error) {
+	if len(s) != len(parts) {
+		return 0, fmt.Errorf("'%s' must have %d characters", s, len(parts))
+	}
+	significant := len(placeholders(parts)) - int(length)
+	result := int64(0)
+	for i, p := range parts {
+		if p.alphabet == "" {
+			if s[i] != p.literal {
+				return 0, fmt.Errorf("'%s' must have '%s' at %d", s, string(p.literal), i)
+			}
+			continue
+		}
+		digit := strings.IndexByte(p.alphabet, s[i])
+		if digit < 0 {
+			return 0, fmt.Errorf("invalid character '%s' encountered at %d while decoding '%s'", string(s[i]), i, s)
+		}
+		if significant > 0 {
+			significant--
+			if digit != 0 {
+				return 0, fmt.Errorf("'%s' must have '%s' at %d with the current length %d", s, string(p.alphabet[0]), i, length)
+			}
+			continue
+		}
+		result = result*int64(len(p.alphabet)) + int64(digit)
+	}
+	return result, nil
+}
@@ -0,0 +1,199 @@
+package hfid
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		template     string
+		placeholders []string
+		wantErr      bool
+	}{
+		{"Parses letters and digits", "AA-9", []string{letters, letters, digits}, false},
+		{"Parses Encoding placeholders", "X", []string{"abc"}, false},
+		{"Parses custom alphabets", "[XYZ]9", []string{"XYZ", digits}, false},
+		{"Parses escaped placeholders as literals", `\A\[\\9`, []string{digits}, false},
+		{"Fails with an unclosed alphabet", "[ABC", nil, true},
+		{"Fails with an invalid alphabet", "[AA]", nil, true},
+		{"Fails with a trailing escape", `A\`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := parseTemplate(tt.template, "abc")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var got []string
+			for _, p := range placeholders(parts) {
+				got = append(got, p.alphabet)
+			}
+			assert.Equal(t, tt.placeholders, got)
+		})
+	}
+}
+
+func TestGenerator_template(t *testing.T) {
+	g := Generator{Name: "a", Prefix: "T-", Encoding: NumericEncoding, Length: 4, Template: "AA-99"}
+
+	t.Run("Counts the HFIDs of the last Length placeholders", func(t *testing.T) {
+		got, err := g.countHFIDs()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(26*26*10*10), got)
+
+		short := g
+		short.Length = 3
+		got, err = short.countHFIDs()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(26*10*10), got)
+	})
+
+	t.Run("Fails when the Length is greater than the placeholders", func(t *testing.T) {
+		long := g
+		long.Length = 5
+		_, err := long.maxHFID()
+		assert.Error(t, err)
+	})
+
+	tests := []struct {
+		name   string
+		length uint8
+		n      int64
+		want   string
+	}{
+		{"Encodes zero", 4, 0, "T-AA-00"},
+		{"Encodes the least significant placeholder last", 4, 7, "T-AA-07"},
+		{"Carries to the next placeholder", 4, 100, "T-AB-00"},
+		{"Encodes the maximum number", 4, 26*26*10*10 - 1, "T-ZZ-99"},
+		{"Sets the unused placeholders to the first character", 3, 26*10*10 - 1, "T-AZ-99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := g
+			it.Length = tt.length
+			got, err := it.encodeHFID(tt.n)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			n, err := it.decodeHFID(got)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.n, n)
+		})
+	}
+
+	t.Run("Fails to encode a number that is too large", func(t *testing.T) {
+		_, err := g.encodeHFID(26 * 26 * 10 * 10)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decodes HFIDs generated before the Length was increased", func(t *testing.T) {
+		short := g
+		short.Length = 3
+		hfid, err := short.encodeHFID(123)
+		assert.NoError(t, err)
+		n, err := g.decodeHFID(hfid)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(123), n)
+	})
+
+	decodeTests := []struct {
+		name   string
+		length uint8
+		hfid   string
+	}{
+		{"Fails if a literal is different", 4, "T-AA_00"},
+		{"Fails if a character is outside the alphabet", 4, "T-A1-00"},
+		{"Fails if the HFID is too short", 4, "T-AA-0"},
+		{"Fails if the HFID is too long", 4, "T-AA-000"},
+		{"Fails if an unused placeholder isn't the first character", 3, "T-BA-00"},
+	}
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := g
+			it.Length = tt.length
+			_, err := it.decodeHFID(tt.hfid)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGenerator_WithTemplate(t *testing.T) {
+	g := Generator{Name: "a", Encoding: DefaultEncoding, MinLength: 1, Length: 8}
+
+	got, err := g.WithTemplate("INV-2026-XXXX-XXXX")
+	assert.NoError(t, err)
+	assert.Equal(t, "INV-2026-XXXX-XXXX", got.Template)
+
+	_, err = g.WithTemplate("AAA-999")
+	assert.Error(t, err, "fails when there are fewer placeholders than the Length")
+
+	_, err = g.WithTemplate("[AB")
+	assert.Error(t, err)
+}
+
+func TestParse_template(t *testing.T) {
+	// The HFIDs of the template are as long as the fallback HFIDs of DefaultEncoding
+	g := Generator{Name: "a", Prefix: "T-", Encoding: DefaultEncoding, MinLength: 1, Length: 4}
+	tg, err := g.WithTemplate(strings.Repeat("X", fallbackLength(g)))
+	assert.NoError(t, err)
+
+	id, err := tg.encodeHFID(123)
+	assert.NoError(t, err)
+	n, err := Parse(*tg, id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), n)
+
+	fallbackID, err := FallbackHFID(*tg)
+	assert.NoError(t, err)
+	_, err = Parse(*tg, fallbackID)
+	assert.ErrorAs(t, err, &FallbackError{})
+}
+
+func TestHFID_template(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewGenerator("a", "", NumericEncoding, 1, 2)
+	assert.NoError(t, err)
+	tg, err := g.WithTemplate("AAA-999")
+	assert.NoError(t, err)
+
+	t.Run("Generates HFIDs using the template", func(t *testing.T) {
+		mcs := NewMockClaimerStore(t)
+		mcs.On("InsertOrGet", ctx, *tg).Return(*g, int64(0), nil)
+		mcs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
+
+		for i := 0; i < 20; i++ {
+			id, err := HFID(ctx, *tg, mcs, *rand.New(rand.NewSource(int64(i))))
+			assert.NoError(t, err)
+			assert.Regexp(t, "^AAA-[0-9]{3}$", id)
+			n, err := Parse(*tg, id)
+			assert.NoError(t, err)
+			assert.Less(t, n, int64(100))
+		}
+		mcs.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Increases the Length when 50% of the HFIDs have been generated", func(t *testing.T) {
+		mgs := NewMockGeneratorStore(t)
+		newG := *tg
+		newG.Length++
+		mgs.On("InsertOrGet", ctx, *tg).Return(*g, int64(50), nil)
+		mgs.On("Upsert", ctx, newG).Return(nil)
+		mgs.On("Add", ctx, mock.Anything, g.Name).Return(true, nil)
+
+		id, err := HFID(ctx, *tg, mgs, *rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Regexp(t, "^AAA-[0-9]{3}$", id)
+		n, err := Parse(newG, id)
+		assert.NoError(t, err)
+		assert.Less(t, n, int64(1000))
+	})
+}